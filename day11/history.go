@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gverger/advent2021/utils/automaton"
+)
+
+// History is the record of a simulation.
+type History struct {
+	// Flashes are the octopuses flashing at each step, Flashes[0] being the
+	// first step.
+	Flashes [][]automaton.Point
+	// The state of the cavern after CycleStart steps repeats every Period
+	// steps. Period is 0 when no cycle was found.
+	CycleStart int
+	Period     int
+}
+
+// Simulate steps until the state of the cavern repeats, at most maxSteps
+// times.
+func (c *Cavern) Simulate(maxSteps int) History {
+	h := History{Flashes: make([][]automaton.Point, 0)}
+
+	seen := map[string]int{c.state(): 0}
+	for step := 1; step <= maxSteps; step++ {
+		h.Flashes = append(h.Flashes, c.octopuses.Step())
+
+		state := c.state()
+		if first, ok := seen[state]; ok {
+			h.CycleStart = first
+			h.Period = step - first
+			break
+		}
+		seen[state] = step
+	}
+
+	return h
+}
+
+// state is a key identifying the energy of all the octopuses.
+func (c *Cavern) state() string {
+	cells := c.energy.Cells()
+	key := make([]byte, len(cells))
+	for i, e := range cells {
+		key[i] = byte(e)
+	}
+
+	return string(key)
+}
+
+// FlashCounts returns the number of flashes of each recorded step.
+func (h History) FlashCounts() []int {
+	res := make([]int, len(h.Flashes))
+	for i, f := range h.Flashes {
+		res[i] = len(f)
+	}
+
+	return res
+}
+
+// FlashesAt returns the number of flashes during a step, starting at 1,
+// extrapolated from the cycle when the step was not simulated.
+func (h History) FlashesAt(step int) (int, error) {
+	if step < 1 {
+		return 0, fmt.Errorf("step %d: steps start at 1", step)
+	}
+	if step <= len(h.Flashes) {
+		return len(h.Flashes[step-1]), nil
+	}
+	if h.Period == 0 {
+		return 0, errors.New("no cycle found to extrapolate from")
+	}
+
+	return len(h.Flashes[h.CycleStart+(step-h.CycleStart-1)%h.Period]), nil
+}
+
+// TotalFlashes returns the number of flashes during the first steps,
+// extrapolated from the cycle when they were not all simulated.
+func (h History) TotalFlashes(steps int) (int, error) {
+	if steps < 1 {
+		return 0, fmt.Errorf("%d steps: there must be at least 1", steps)
+	}
+	if steps <= len(h.Flashes) {
+		return sum(h.FlashCounts()[:steps]), nil
+	}
+	if h.Period == 0 {
+		return 0, errors.New("no cycle found to extrapolate from")
+	}
+
+	counts := h.FlashCounts()
+	cycle := counts[h.CycleStart : h.CycleStart+h.Period]
+	remaining := steps - h.CycleStart
+
+	return sum(counts[:h.CycleStart]) + remaining/h.Period*sum(cycle) + sum(cycle[:remaining%h.Period]), nil
+}
+
+func sum(values []int) int {
+	s := 0
+	for _, v := range values {
+		s += v
+	}
+
+	return s
+}
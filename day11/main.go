@@ -1,12 +1,20 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/gverger/advent2021/utils"
-	"github.com/gverger/advent2021/utils/maps"
+	"github.com/gverger/advent2021/utils/automaton"
+)
+
+var (
+	wrap     = flag.Bool("wrap", false, "octopuses on an edge are neighbours of the ones on the opposite edge")
+	maxSteps = flag.Int("max-steps", 100000, "number of steps after which the simulations give up")
+	total    = flag.Int("total", 0, "number of steps to count the flashes of, extrapolated once the cavern cycles")
 )
 
 func main() {
@@ -14,8 +22,46 @@ func main() {
 }
 
 func run(lines []string) error {
-	part1(NewCavernFromInput(lines))
-	part2(NewCavernFromInput(lines))
+	if *maxSteps < 1 {
+		return fmt.Errorf("-max-steps %d must be at least 1", *maxSteps)
+	}
+	if *total < 0 {
+		return fmt.Errorf("-total %d cannot be negative", *total)
+	}
+
+	boundary := automaton.Finite
+	if *wrap {
+		boundary = automaton.Wrap
+	}
+
+	c, err := NewCavern(lines, boundary)
+	if err != nil {
+		return err
+	}
+	part1(c)
+
+	c, err = NewCavern(lines, boundary)
+	if err != nil {
+		return err
+	}
+	part2(c)
+
+	if *total > 0 {
+		c, err = NewCavern(lines, boundary)
+		if err != nil {
+			return err
+		}
+		h := c.Simulate(*maxSteps)
+		if h.Period > 0 {
+			fmt.Printf("Cycle of %d steps from step %d\n", h.Period, h.CycleStart)
+		}
+
+		nb, err := h.TotalFlashes(*total)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Nb of flashes in %d steps: %d\n", *total, nb)
+	}
 
 	return nil
 }
@@ -32,7 +78,11 @@ func part1(c Cavern) {
 func part2(c Cavern) {
 	nb := 0
 	step := 0
-	for nb != 100 {
+	for nb != c.Size() {
+		if step == *maxSteps {
+			fmt.Println("Part2: not synchronized after", step, "steps")
+			return
+		}
 		step += 1
 		nb = c.Step()
 	}
@@ -41,119 +91,85 @@ func part2(c Cavern) {
 }
 
 type Cavern struct {
-	energy [][]int
-}
-
-func NewCavernFromInput(lines []string) Cavern {
-	energy := make([][]int, 10)
-	c := Cavern{energy: energy}
-	for i := range energy {
-		energy[i] = make([]int, 10)
-	}
-
-	for row, l := range lines {
-		for col, char := range l {
-			energy, _ := strconv.Atoi(string(char))
-			c.Set(col, row, energy)
-		}
-	}
-
-	return c
+	energy    *automaton.Grid
+	octopuses *automaton.Automaton
 }
 
-func (c *Cavern) Set(x int, y int, energy int) {
-	if x < 0 || y < 0 || x > 9 || y > 9 {
-		return
-	}
-	c.energy[y][x] = energy
+// flashes increases the energy of every octopus. Octopuses above 9 flash,
+// increasing the energy of their neighbours, and go back to 0.
+var flashes = automaton.Cascade{
+	Update:    incr,
+	Fires:     func(s automaton.State) bool { return s > 9 },
+	Fired:     0,
+	Propagate: incr,
 }
 
-func (c *Cavern) Incr(x int, y int) int {
-	if x < 0 || y < 0 || x > 9 || y > 9 {
-		return -1
-	}
-	if c.energy[y][x] <= 9 {
-		c.energy[y][x] = c.energy[y][x] + 1
-	}
-
-	return c.energy[y][x]
+func incr(s automaton.State) automaton.State {
+	return s + 1
 }
 
-func (c *Cavern) Get(x int, y int) int {
-	return c.energy[y][x]
+func NewCavernFromInput(lines []string) (Cavern, error) {
+	return NewCavern(lines, automaton.Finite)
 }
 
-func (c *Cavern) Step() int {
-	nbFlashes := 0
-	flashes := make([]Position, 0)
-
-	incr := func(p Position) {
-		c.Incr(p.x, p.y)
-		if c.Get(p.x, p.y) > 9 {
-			c.Set(p.x, p.y, 0)
-			flashes = append(flashes, p)
-			nbFlashes++
-		}
-	}
-
-	for y, row := range c.energy {
-		for x := range row {
-			incr(Position{x: x, y: y})
-		}
+// NewCavern returns a cavern of the size of the input, with toroidal edges
+// when boundary is automaton.Wrap.
+func NewCavern(lines []string, boundary automaton.Boundary) (Cavern, error) {
+	if len(lines) == 0 {
+		return Cavern{}, errors.New("no octopus")
 	}
 
-	for len(flashes) > 0 {
-		f := flashes[0]
-		flashes = flashes[1:]
+	energy := automaton.NewGrid(len(lines[0]), len(lines), boundary)
+	c := Cavern{energy: energy, octopuses: automaton.New(energy, automaton.Moore, flashes)}
 
-		for _, p := range f.Neighbours() {
-			if c.Get(p.x, p.y) == 0 {
-				continue
+	for row, l := range lines {
+		if len(l) != energy.Width {
+			return c, fmt.Errorf("line %d: %d octopuses instead of %d", row+1, len(l), energy.Width)
+		}
+		for col, char := range l {
+			energy, err := strconv.Atoi(string(char))
+			if err != nil {
+				return c, fmt.Errorf("line %d: invalid energy %q", row+1, char)
 			}
-
-			incr(p)
+			c.Set(col, row, energy)
 		}
 	}
 
-	return nbFlashes
+	return c, nil
 }
 
-type Position struct {
-	x int
-	y int
+// Size is the number of octopuses.
+func (c Cavern) Size() int {
+	return c.energy.Width * c.energy.Height
 }
 
-func (p Position) IsValid() bool {
-	return p.x >= 0 && p.y >= 0 && p.x <= 9 && p.y <= 9
+func (c *Cavern) Set(x int, y int, energy int) {
+	c.energy.Set(automaton.Point{X: x, Y: y}, automaton.State(energy))
 }
 
-func (p Position) Neighbours() []Position {
-	neighbours := make([]Position, 0)
-	for dx := -1; dx <= 1; dx++ {
-		for dy := -1; dy <= 1; dy++ {
-			x := p.x + dx
-			y := p.y + dy
-			p := Position{x: x, y: y}
-			if p.IsValid() {
-				neighbours = append(neighbours, p)
-			}
-		}
-	}
+func (c *Cavern) Get(x int, y int) int {
+	return int(c.energy.At(automaton.Point{X: x, Y: y}))
+}
 
-	return neighbours
+// Step returns the number of flashes.
+func (c *Cavern) Step() int {
+	return len(c.octopuses.Step())
 }
 
 func (c Cavern) String() string {
-	res := make([]string, 0, len(c.energy))
-	for _, row := range c.energy {
-		sRow, _ := maps.Ints(row).ToStrings()
-		for i, c := range sRow {
-			if c == "10" {
-				sRow[i] = "X"
+	res := make([]string, 0, c.energy.Height)
+	for y := 0; y < c.energy.Height; y++ {
+		var row strings.Builder
+		for x := 0; x < c.energy.Width; x++ {
+			e := c.Get(x, y)
+			if e > 9 {
+				row.WriteString("X")
+			} else {
+				row.WriteString(strconv.Itoa(e))
 			}
 		}
 
-		res = append(res, strings.Join(sRow, ""))
+		res = append(res, row.String())
 	}
 
 	return strings.Join(res, "\n")
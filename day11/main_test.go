@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gverger/advent2021/utils"
+	"github.com/gverger/advent2021/utils/automaton"
 	"github.com/gverger/advent2021/utils/maps"
 	"github.com/stretchr/testify/require"
 )
@@ -72,7 +74,7 @@ func TestStep(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			c := NewCavernFromInput(test.input)
+			c := newCavern(t, test.input)
 			c.Step()
 			require.Equal(t, test.output, cavernState(c))
 		})
@@ -80,11 +82,198 @@ func TestStep(t *testing.T) {
 }
 
 func cavernState(c Cavern) []string {
-	res := make([]string, len(c.energy))
-	for i, row := range c.energy {
+	res := make([]string, c.energy.Height)
+	for y := range res {
+		row := make([]int, c.energy.Width)
+		for x := range row {
+			row[x] = c.Get(x, y)
+		}
 		s, _ := maps.Ints(row).ToStrings()
-		res[i] = strings.Join(s, "")
+		res[y] = strings.Join(s, "")
 	}
 
 	return res
 }
+
+func TestCavernSizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		boundary automaton.Boundary
+		input    []string
+		output   []string
+	}{
+		{
+			name:     "small",
+			boundary: automaton.Finite,
+			input: []string{
+				"11111",
+				"19991",
+				"19191",
+				"19991",
+				"11111",
+			},
+			output: []string{
+				"34543",
+				"40004",
+				"50005",
+				"40004",
+				"34543",
+			},
+		},
+		{
+			name:     "not square",
+			boundary: automaton.Finite,
+			input: []string{
+				"119",
+				"111",
+			},
+			output: []string{
+				"230",
+				"233",
+			},
+		},
+		{
+			name:     "wrap",
+			boundary: automaton.Wrap,
+			input: []string{
+				"1119",
+				"1111",
+				"1111",
+			},
+			output: []string{
+				"3230",
+				"3233",
+				"3233",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := NewCavern(test.input, test.boundary)
+			require.NoError(t, err)
+			require.Equal(t, len(test.input)*len(test.input[0]), c.Size())
+
+			c.Step()
+			require.Equal(t, test.output, cavernState(c))
+		})
+	}
+}
+
+func TestNewCavernErrors(t *testing.T) {
+	_, err := NewCavern([]string{"123", "12"}, automaton.Finite)
+	require.EqualError(t, err, "line 2: 2 octopuses instead of 3")
+
+	_, err = NewCavern([]string{"123", "1x3"}, automaton.Finite)
+	require.EqualError(t, err, "line 2: invalid energy 'x'")
+
+	_, err = NewCavern(nil, automaton.Finite)
+	require.EqualError(t, err, "no octopus")
+}
+
+func TestSimulate(t *testing.T) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+	c := newCavern(t, lines)
+
+	h := c.Simulate(1000)
+
+	require.Equal(t, 195, h.CycleStart)
+	require.Equal(t, 10, h.Period)
+	require.Len(t, h.Flashes, 205)
+	require.Equal(t, []int{0, 35}, h.FlashCounts()[:2])
+	require.Len(t, h.Flashes[194], 100)
+
+	total, err := h.TotalFlashes(100)
+	require.NoError(t, err)
+	require.Equal(t, 1656, total)
+
+	for _, steps := range []int{205, 206, 300, 1000} {
+		c := newCavern(t, lines)
+		expected := 0
+		for i := 0; i < steps; i++ {
+			expected += c.Step()
+		}
+
+		total, err := h.TotalFlashes(steps)
+		require.NoError(t, err)
+		require.Equal(t, expected, total, "after %d steps", steps)
+
+		// once synchronized, octopuses all flash every 10 steps
+		expectedLast := 0
+		if (steps-195)%10 == 0 {
+			expectedLast = 100
+		}
+		last, err := h.FlashesAt(steps)
+		require.NoError(t, err)
+		require.Equal(t, expectedLast, last)
+	}
+
+	total, err = h.TotalFlashes(1_000_000_000_000)
+	require.NoError(t, err)
+	require.Equal(t, sum(h.FlashCounts()[:195])+(1_000_000_000_000-195)/10*100, total)
+}
+
+func TestSimulateWithoutCycle(t *testing.T) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+	c := newCavern(t, lines)
+
+	h := c.Simulate(10)
+
+	require.Equal(t, 0, h.Period)
+	_, err = h.TotalFlashes(11)
+	require.Error(t, err)
+}
+
+func newCavern(t *testing.T, lines []string) Cavern {
+	c, err := NewCavernFromInput(lines)
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestHistoryInvalidSteps(t *testing.T) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+	c := newCavern(t, lines)
+	h := c.Simulate(1000)
+
+	for _, step := range []int{0, -1} {
+		_, err = h.FlashesAt(step)
+		require.Error(t, err)
+		_, err = h.TotalFlashes(step)
+		require.Error(t, err)
+	}
+
+	nb, err := h.FlashesAt(1)
+	require.NoError(t, err)
+	require.Equal(t, 0, nb)
+	nb, err = h.TotalFlashes(1)
+	require.NoError(t, err)
+	require.Equal(t, 0, nb)
+}
+
+func TestRunInvalidFlags(t *testing.T) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+
+	tests := []struct {
+		flag  *int
+		value int
+		err   string
+	}{
+		{flag: maxSteps, value: 0, err: "-max-steps 0 must be at least 1"},
+		{flag: maxSteps, value: -3, err: "-max-steps -3 must be at least 1"},
+		{flag: total, value: -1, err: "-total -1 cannot be negative"},
+	}
+
+	for _, test := range tests {
+		previous := *test.flag
+		*test.flag = test.value
+		err := run(lines)
+		*test.flag = previous
+
+		require.EqualError(t, err, test.err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Check returns an error when the paths of the policy cannot be searched:
+// missing start or end, end not reachable from start, or infinitely many
+// paths.
+func (g Graph) Check(policy Policy) error {
+	for _, cave := range []string{policy.Start, policy.End} {
+		if _, ok := g.edge[cave]; !ok {
+			return fmt.Errorf("missing cave %q", cave)
+		}
+	}
+
+	for _, cave := range g.Unreachable(policy.Start) {
+		if cave == policy.End {
+			return fmt.Errorf("cave %q cannot be reached from %q", policy.End, policy.Start)
+		}
+	}
+
+	if from, to, ok := g.InfiniteLoop(policy); ok {
+		return fmt.Errorf("infinite number of paths: %q and %q are adjacent unlimited caves", from, to)
+	}
+
+	return nil
+}
+
+// Unreachable returns the sorted caves with no path from start.
+func (g Graph) Unreachable(start string) []string {
+	seen := g.reachable(start, "")
+
+	res := make([]string, 0)
+	for _, cave := range g.Caves() {
+		if !seen[cave] {
+			res = append(res, cave)
+		}
+	}
+
+	return res
+}
+
+// reachable returns the caves with a path from start that does not go through
+// the cave `through`.
+func (g Graph) reachable(start string, through string) map[string]bool {
+	seen := map[string]bool{start: true}
+	todo := []string{start}
+	for len(todo) > 0 {
+		cave := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if cave == through {
+			continue
+		}
+		for _, to := range g.edge[cave] {
+			if !seen[to] {
+				seen[to] = true
+				todo = append(todo, to)
+			}
+		}
+	}
+
+	return seen
+}
+
+// InfiniteLoop returns two adjacent caves without a visit limit, on a path
+// from start to end. A path can go back and forth between them forever, so
+// there are infinitely many paths and the search would never end.
+func (g Graph) InfiniteLoop(policy Policy) (string, string, bool) {
+	fromStart := g.reachable(policy.Start, policy.End)
+	toEnd := g.reachable(policy.End, policy.Start)
+	onPath := func(cave string) bool {
+		_, limited := policy.limit(cave)
+		return !limited && fromStart[cave] && toEnd[cave]
+	}
+
+	for _, from := range g.Caves() {
+		if !onPath(from) {
+			continue
+		}
+		for _, to := range g.edge[from] {
+			if onPath(to) {
+				return from, to, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// Caves returns the sorted names of the caves.
+func (g Graph) Caves() []string {
+	caves := make([]string, 0, len(g.edge))
+	for cave := range g.edge {
+		caves = append(caves, cave)
+	}
+	sort.Strings(caves)
+
+	return caves
+}
+
+// DOT returns the graph in the Graphviz format. Big caves are drawn as filled
+// boxes, small caves as circles, and the start and end as double circles.
+func (g Graph) DOT(policy Policy) string {
+	var builder strings.Builder
+	builder.WriteString("graph caves {\n")
+
+	for _, cave := range g.Caves() {
+		style := "shape=circle"
+		switch {
+		case cave == policy.Start || cave == policy.End:
+			style = "shape=doublecircle"
+		case policy.isBig(cave):
+			style = "shape=box, style=filled, fillcolor=lightgrey"
+		}
+		builder.WriteString(fmt.Sprintf("  %q [%s];\n", cave, style))
+	}
+
+	for _, from := range g.Caves() {
+		tos := append([]string(nil), g.edge[from]...)
+		sort.Strings(tos)
+		for _, to := range tos {
+			if from < to {
+				builder.WriteString(fmt.Sprintf("  %q -- %q;\n", from, to))
+			}
+		}
+	}
+
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// CountPaths counts the paths allowed by the policy without enumerating them.
+func (g Graph) CountPaths(policy Policy) (*big.Int, error) {
+	if err := g.Check(policy); err != nil {
+		return nil, err
+	}
+
+	c, err := newPathCounter(g, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	visits := make([]byte, len(c.index))
+	if i, ok := c.index[policy.Start]; ok {
+		visits[i] = 1
+	}
+
+	return c.count(policy.Start, visits, 0), nil
+}
+
+type countKey struct {
+	node     string
+	visits   string
+	revisits int
+}
+
+type pathCounter struct {
+	g      Graph
+	policy Policy
+	// index of the caves with a limited number of visits in the visits slice.
+	index map[string]int
+	memo  map[countKey]*big.Int
+}
+
+func newPathCounter(g Graph, policy Policy) (*pathCounter, error) {
+	if policy.RevisitLimit > math.MaxUint8 {
+		return nil, fmt.Errorf("revisit limit %d is too high", policy.RevisitLimit)
+	}
+
+	c := &pathCounter{
+		g:      g,
+		policy: policy,
+		index:  make(map[string]int),
+		memo:   make(map[countKey]*big.Int),
+	}
+
+	for node := range g.edge {
+		limit, limited := policy.limit(node)
+		if !limited {
+			continue
+		}
+		if limit > math.MaxUint8 {
+			return nil, fmt.Errorf("limit %d of cave %q is too high", limit, node)
+		}
+		c.index[node] = len(c.index)
+	}
+
+	return c, nil
+}
+
+func (c *pathCounter) count(node string, visits []byte, revisits int) *big.Int {
+	if node == c.policy.End {
+		return big.NewInt(1)
+	}
+
+	key := countKey{node: node, visits: string(visits), revisits: revisits}
+	if nb, ok := c.memo[key]; ok {
+		return nb
+	}
+
+	nb := new(big.Int)
+	for _, to := range c.g.edge[node] {
+		i, tracked := c.index[to]
+		nbVisits := 0
+		if tracked {
+			nbVisits = int(visits[i])
+		}
+
+		allowed, revisit := c.policy.allows(to, nbVisits, revisits)
+		if !allowed {
+			continue
+		}
+
+		next := visits
+		if tracked {
+			next = append([]byte(nil), visits...)
+			next[i]++
+		}
+		nextRevisits := revisits
+		if revisit {
+			nextRevisits++
+		}
+
+		nb.Add(nb, c.count(to, next, nextRevisits))
+	}
+
+	c.memo[key] = nb
+
+	return nb
+}
@@ -1,45 +1,44 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"strings"
-	"unicode"
 
 	"github.com/gverger/advent2021/utils"
 )
 
+var dot = flag.Bool("dot", false, "print the graph in the DOT format")
+
 func main() {
 	utils.Main(run)
 }
 
 func run(lines []string) error {
+	g := NewGraphFromInput(lines)
 
-	fmt.Println("Nb paths (part 1):", part1(NewGraphFromInput(lines)))
-	fmt.Println("Nb paths (part 2):", part2(NewGraphFromInput(lines)))
-
-	return nil
-}
-
-func part1(g Graph) int {
-	nb := 0
-	fn := func(_ Path) {
-		nb++
+	if *dot {
+		fmt.Print(g.DOT(Part1Policy()))
+		return nil
 	}
 
-	g.Part1Paths(fn)
-
-	return nb
-}
+	if unreachable := g.Unreachable("start"); len(unreachable) > 0 {
+		fmt.Println("WARNING: unreachable caves:", unreachable)
+	}
 
-func part2(g Graph) int {
-	nb := 0
-	fn := func(_ Path) {
-		nb++
+	nb, err := g.CountPaths(Part1Policy())
+	if err != nil {
+		return err
 	}
+	fmt.Println("Nb paths (part 1):", nb)
 
-	g.Part2Paths(fn)
+	nb, err = g.CountPaths(Part2Policy())
+	if err != nil {
+		return err
+	}
+	fmt.Println("Nb paths (part 2):", nb)
 
-	return nb
+	return nil
 }
 
 type Graph struct {
@@ -57,21 +56,31 @@ func NewGraphFromInput(lines []string) Graph {
 	return g
 }
 
-func (g Graph) Part1Paths(exec func(path Path)) {
-	p := NewPathPart1()
-	p.Append("start")
-	g.pathsFrom("start", &p, exec)
+func (g Graph) Part1Paths(exec func(path Path)) error {
+	return g.Paths(Part1Policy(), exec)
 }
 
-func (g Graph) Part2Paths(exec func(path Path)) {
-	p := NewPathPart2()
-	p.Append("start")
-	g.pathsFrom("start", &p, exec)
+func (g Graph) Part2Paths(exec func(path Path)) error {
+	return g.Paths(Part2Policy(), exec)
 }
 
-func (g Graph) pathsFrom(start string, done Path, exec func(path Path)) {
-	if start == "end" {
+// Paths calls exec on every path allowed by the policy.
+func (g Graph) Paths(policy Policy, exec func(path Path)) error {
+	if err := g.Check(policy); err != nil {
+		return err
+	}
+
+	p := NewPolicyPath(policy)
+	p.Append(policy.Start)
+	g.pathsFrom(policy.Start, policy.End, &p, exec)
+
+	return nil
+}
+
+func (g Graph) pathsFrom(start string, end string, done Path, exec func(path Path)) {
+	if start == end {
 		exec(done)
+		return
 	}
 
 	for _, to := range g.edge[start] {
@@ -79,7 +88,7 @@ func (g Graph) pathsFrom(start string, done Path, exec func(path Path)) {
 			continue
 		}
 		done.Append(to)
-		g.pathsFrom(to, done, exec)
+		g.pathsFrom(to, end, done, exec)
 		done.DeleteLast()
 	}
 }
@@ -91,8 +100,8 @@ func (g *Graph) AddEdge(from string, to string) {
 
 func (g Graph) String() string {
 	var builder strings.Builder
-	for from, tos := range g.edge {
-		builder.WriteString(fmt.Sprintf("%s --> %v\n", from, tos))
+	for _, from := range g.Caves() {
+		builder.WriteString(fmt.Sprintf("%s --> %v\n", from, g.edge[from]))
 	}
 
 	return builder.String()
@@ -104,85 +113,3 @@ type Path interface {
 	DeleteLast()
 	String() string
 }
-
-type PathPart1 struct {
-	visited map[string]struct{}
-	path    []string
-}
-
-func NewPathPart1() PathPart1 {
-	return PathPart1{visited: make(map[string]struct{}), path: make([]string, 0)}
-}
-
-func (p PathPart1) Visited(node string) bool {
-	if unicode.IsUpper(rune(node[0])) {
-		return false
-	}
-	_, ok := p.visited[node]
-
-	return ok
-}
-
-func (p *PathPart1) Append(node string) {
-	p.path = append(p.path, node)
-	p.visited[node] = struct{}{}
-}
-
-func (p *PathPart1) DeleteLast() {
-	last := p.path[len(p.path)-1]
-	delete(p.visited, last)
-
-	p.path = p.path[:len(p.path)-1]
-}
-
-func (p PathPart1) String() string {
-	return strings.Join(p.path, " --> ")
-}
-
-type PathPart2 struct {
-	visited map[string]int
-	path    []string
-}
-
-func NewPathPart2() PathPart2 {
-	return PathPart2{visited: make(map[string]int), path: make([]string, 0)}
-}
-
-func (p PathPart2) Visited(node string) bool {
-	if unicode.IsUpper(rune(node[0])) {
-		return false
-	}
-	nb := p.visited[node]
-
-	if nb == 0 {
-		return false
-	}
-
-	for _, n := range p.path {
-		if p.visited[n] == 2 && unicode.IsLower(rune(n[0])) {
-			return true
-		}
-	}
-
-	if node == "start" || node == "end" {
-		return true
-	}
-
-	return false
-}
-
-func (p *PathPart2) Append(node string) {
-	p.path = append(p.path, node)
-	p.visited[node]++
-}
-
-func (p *PathPart2) DeleteLast() {
-	last := p.path[len(p.path)-1]
-	p.visited[last]--
-
-	p.path = p.path[:len(p.path)-1]
-}
-
-func (p PathPart2) String() string {
-	return strings.Join(p.path, ",")
-}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func graphFromFile(t *testing.T, filename string) Graph {
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	return NewGraphFromInput(strings.Split(strings.TrimSpace(string(content)), "\n"))
+}
+
+// enumerate counts the paths of the Paths enumeration.
+func enumerate(t *testing.T, g Graph, policy Policy) int64 {
+	var nb int64
+	require.NoError(t, g.Paths(policy, func(path Path) { nb++ }))
+
+	return nb
+}
+
+func TestCountPaths(t *testing.T) {
+	tests := []struct {
+		filename string
+		part1    int64
+		part2    int64
+	}{
+		{filename: "simple.txt", part1: 10, part2: 36},
+		{filename: "test.txt", part1: 226, part2: 3509},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filename, func(t *testing.T) {
+			g := graphFromFile(t, test.filename)
+
+			nb, err := g.CountPaths(Part1Policy())
+			require.NoError(t, err)
+			require.Equal(t, big.NewInt(test.part1), nb)
+			require.Equal(t, test.part1, enumerate(t, g, Part1Policy()))
+
+			nb, err = g.CountPaths(Part2Policy())
+			require.NoError(t, err)
+			require.Equal(t, big.NewInt(test.part2), nb)
+			require.Equal(t, test.part2, enumerate(t, g, Part2Policy()))
+		})
+	}
+}
+
+func TestPolicyVariants(t *testing.T) {
+	custom := func(change func(p *Policy)) Policy {
+		p := Part1Policy()
+		change(&p)
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		policy   Policy
+		expected int64
+	}{
+		{name: "start and end", filename: "simple.txt", policy: custom(func(p *Policy) { p.Start, p.End = "c", "d" }), expected: 9},
+		{name: "limit of a big cave", filename: "simple.txt", policy: custom(func(p *Policy) { p.Limits = map[string]int{"A": 2} }), expected: 8},
+		{name: "limit of a small cave", filename: "test.txt", policy: custom(func(p *Policy) { p.Limits = map[string]int{"zg": 2, "pj": 0} }), expected: 48},
+		{name: "two revisits", filename: "simple.txt", policy: custom(func(p *Policy) { p.Revisits, p.RevisitLimit = 2, 3 }), expected: 267},
+		{name: "custom big caves", filename: "test.txt", policy: custom(func(p *Policy) {
+			p.IsBig = func(cave string) bool { return cave == "zg" }
+		}), expected: 398},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filename+" "+test.name, func(t *testing.T) {
+			g := graphFromFile(t, test.filename)
+
+			nb, err := g.CountPaths(test.policy)
+			require.NoError(t, err)
+			require.Equal(t, big.NewInt(test.expected), nb)
+			require.Equal(t, test.expected, enumerate(t, g, test.policy))
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		err   string
+	}{
+		{name: "valid", lines: []string{"start-A", "A-b", "A-end"}},
+		{name: "missing start", lines: []string{"begin-A", "A-end"}, err: `missing cave "start"`},
+		{name: "missing end", lines: []string{"start-A", "A-b"}, err: `missing cave "end"`},
+		{name: "unreachable end", lines: []string{"start-A", "A-b", "c-end"}, err: `cave "end" cannot be reached from "start"`},
+		{name: "adjacent big caves", lines: []string{"start-A", "A-B", "B-end"}, err: `"A" and "B" are adjacent unlimited caves`},
+		{name: "unreachable big caves", lines: []string{"start-A", "A-end", "B-C", "C-d"}},
+		{name: "big caves after end", lines: []string{"start-a", "a-end", "end-B", "B-C"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := NewGraphFromInput(test.lines)
+
+			err := g.Check(Part1Policy())
+			_, countErr := g.CountPaths(Part1Policy())
+			pathsErr := g.Paths(Part1Policy(), func(path Path) {})
+			if test.err == "" {
+				require.NoError(t, err)
+				require.NoError(t, countErr)
+				require.NoError(t, pathsErr)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.err)
+			require.Equal(t, err, countErr)
+			require.Equal(t, err, pathsErr)
+		})
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	g := NewGraphFromInput([]string{"start-A", "A-end", "B-c", "d-e"})
+
+	require.Equal(t, []string{"B", "c", "d", "e"}, g.Unreachable("start"))
+	require.Empty(t, graphFromFile(t, "test.txt").Unreachable("start"))
+}
+
+func TestInfiniteLoop(t *testing.T) {
+	g := NewGraphFromInput([]string{"start-A", "A-B", "B-end"})
+
+	from, to, ok := g.InfiniteLoop(Part1Policy())
+	require.True(t, ok)
+	require.Equal(t, "A", from)
+	require.Equal(t, "B", to)
+
+	p := Part1Policy()
+	p.Limits = map[string]int{"B": 3}
+	_, _, ok = g.InfiniteLoop(p)
+	require.False(t, ok)
+}
+
+func TestDOT(t *testing.T) {
+	expected := `graph caves {
+  "A" [shape=box, style=filled, fillcolor=lightgrey];
+  "b" [shape=circle];
+  "c" [shape=circle];
+  "d" [shape=circle];
+  "end" [shape=doublecircle];
+  "start" [shape=doublecircle];
+  "A" -- "b";
+  "A" -- "c";
+  "A" -- "end";
+  "A" -- "start";
+  "b" -- "d";
+  "b" -- "end";
+  "b" -- "start";
+}
+`
+
+	require.Equal(t, expected, graphFromFile(t, "simple.txt").DOT(Part1Policy()))
+}
+
+// TestCountPathsBeyondInt64 counts the paths of a clique of small caves,
+// all linked to several big hubs. A path is a sequence of distinct small
+// caves, each of its gaps being either a direct edge or a detour through one
+// of the hubs.
+func TestCountPathsBeyondInt64(t *testing.T) {
+	const (
+		nbSmall = 12
+		nbHubs  = 7
+	)
+
+	lines := make([]string, 0)
+	caves := []string{"start", "end"}
+	for i := 0; i < nbHubs; i++ {
+		hub := fmt.Sprintf("H%d", i)
+		lines = append(lines, "start-"+hub, hub+"-end")
+	}
+	for i := 0; i < nbSmall; i++ {
+		cave := fmt.Sprintf("c%d", i)
+		for _, other := range caves {
+			lines = append(lines, other+"-"+cave)
+		}
+		for j := 0; j < nbHubs; j++ {
+			lines = append(lines, fmt.Sprintf("H%d-%s", j, cave))
+		}
+		caves = append(caves, cave)
+	}
+
+	// start and end are only linked through the hubs
+	expected := big.NewInt(nbHubs)
+	arrangements := big.NewInt(1)
+	for m := int64(1); m <= nbSmall; m++ {
+		arrangements.Mul(arrangements, big.NewInt(nbSmall-m+1))
+		gaps := new(big.Int).Exp(big.NewInt(nbHubs+1), big.NewInt(m+1), nil)
+		expected.Add(expected, gaps.Mul(gaps, arrangements))
+	}
+	require.False(t, expected.IsInt64())
+
+	nb, err := NewGraphFromInput(lines).CountPaths(Part1Policy())
+	require.NoError(t, err)
+	require.Equal(t, expected.String(), nb.String())
+}
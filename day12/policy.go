@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Policy tells which caves a path can go through, and how many times.
+type Policy struct {
+	Start string
+	End   string
+
+	// SmallLimit is the number of times a small cave can be visited.
+	SmallLimit int
+	// Limits overrides the number of visits for specific caves, big or small.
+	Limits map[string]int
+
+	// Revisits is the number of caves that can go over their limit.
+	Revisits int
+	// RevisitLimit is the number of visits allowed for such a cave.
+	RevisitLimit int
+
+	// IsBig tells whether a cave is big, ie can be visited any number of
+	// times. Defaults to IsBigCave.
+	IsBig func(cave string) bool
+}
+
+func IsBigCave(cave string) bool {
+	return unicode.IsUpper(rune(cave[0]))
+}
+
+func Part1Policy() Policy {
+	return Policy{Start: "start", End: "end", SmallLimit: 1}
+}
+
+func Part2Policy() Policy {
+	p := Part1Policy()
+	p.Revisits = 1
+	p.RevisitLimit = 2
+
+	return p
+}
+
+func (p Policy) isBig(cave string) bool {
+	if p.IsBig == nil {
+		return IsBigCave(cave)
+	}
+
+	return p.IsBig(cave)
+}
+
+// limit returns the number of visits allowed for a cave, and false if it is
+// unlimited.
+func (p Policy) limit(cave string) (int, bool) {
+	if l, ok := p.Limits[cave]; ok {
+		return l, true
+	}
+	if cave == p.Start || cave == p.End {
+		return 1, true
+	}
+	if p.isBig(cave) {
+		return 0, false
+	}
+
+	return p.SmallLimit, true
+}
+
+// allows tells whether a cave already visited `visits` times can be visited
+// once more, while `revisits` caves are already over their limit. The second
+// value is true when this visit makes the cave go over its limit.
+func (p Policy) allows(cave string, visits int, revisits int) (bool, bool) {
+	if cave == p.Start {
+		return false, false
+	}
+
+	limit, limited := p.limit(cave)
+	if !limited || visits < limit {
+		return true, false
+	}
+	if cave == p.End || visits >= p.RevisitLimit {
+		return false, false
+	}
+	if visits > limit {
+		return true, false
+	}
+
+	return revisits < p.Revisits, true
+}
+
+type PolicyPath struct {
+	policy   Policy
+	visits   map[string]int
+	path     []string
+	revisits int
+}
+
+func NewPolicyPath(policy Policy) PolicyPath {
+	return PolicyPath{policy: policy, visits: make(map[string]int), path: make([]string, 0)}
+}
+
+func (p PolicyPath) Visited(node string) bool {
+	allowed, _ := p.policy.allows(node, p.visits[node], p.revisits)
+
+	return !allowed
+}
+
+func (p *PolicyPath) Append(node string) {
+	p.path = append(p.path, node)
+	p.visits[node]++
+	if p.visits[node] == p.limitPlusOne(node) {
+		p.revisits++
+	}
+}
+
+func (p *PolicyPath) DeleteLast() {
+	last := p.path[len(p.path)-1]
+	if p.visits[last] == p.limitPlusOne(last) {
+		p.revisits--
+	}
+	p.visits[last]--
+
+	p.path = p.path[:len(p.path)-1]
+}
+
+// limitPlusOne returns the number of visits that makes a cave go over its
+// limit, or 0 if it has no limit.
+func (p PolicyPath) limitPlusOne(node string) int {
+	limit, limited := p.policy.limit(node)
+	if !limited {
+		return 0
+	}
+
+	return limit + 1
+}
+
+func (p PolicyPath) String() string {
+	return strings.Join(p.path, ",")
+}
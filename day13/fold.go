@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fold is a fold along the vertical line x=Line, or the horizontal line y=Line.
+type Fold struct {
+	Axis byte
+	Line int
+}
+
+func (f Fold) String() string {
+	return fmt.Sprintf("fold along %c=%d", f.Axis, f.Line)
+}
+
+const foldPrefix = "fold along "
+
+// ParseFold parses a line such as "fold along x=5".
+func ParseFold(line string) (Fold, error) {
+	if !strings.HasPrefix(line, foldPrefix) {
+		return Fold{}, fmt.Errorf("%q: expected %q", line, foldPrefix)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(line, foldPrefix), "=")
+	if len(parts) != 2 {
+		return Fold{}, fmt.Errorf("%q: expected a single '='", line)
+	}
+	if parts[0] != "x" && parts[0] != "y" {
+		return Fold{}, fmt.Errorf("%q: unknown axis %q, expected x or y", line, parts[0])
+	}
+
+	value, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Fold{}, fmt.Errorf("%q: invalid fold line: %w", line, err)
+	}
+	if value < 0 {
+		return Fold{}, fmt.Errorf("%q: negative fold line", line)
+	}
+
+	return Fold{Axis: parts[0][0], Line: value}, nil
+}
+
+func ParseFolds(lines []string) ([]Fold, error) {
+	res := make([]Fold, 0, len(lines))
+	for i, l := range lines {
+		f, err := ParseFold(l)
+		if err != nil {
+			return nil, fmt.Errorf("fold %d: %w", i+1, err)
+		}
+		res = append(res, f)
+	}
+
+	return res, nil
+}
+
+// Check returns warnings when the fold is not at the middle of the sheet, or
+// when dots are on the fold line: they would stay on the sheet while the line
+// disappears.
+func (s Sheet) Check(f Fold) []string {
+	warnings := make([]string, 0)
+
+	max := s.maxX
+	if f.Axis == 'y' {
+		max = s.maxY
+	}
+	if max != 2*f.Line {
+		warnings = append(warnings, fmt.Sprintf("%v is not at the middle of the sheet, from 0 to %d", f, max))
+	}
+
+	onLine := 0
+	for p := range s.dots {
+		if (f.Axis == 'x' && p.x == f.Line) || (f.Axis == 'y' && p.y == f.Line) {
+			onLine++
+		}
+	}
+	if onLine > 0 {
+		warnings = append(warnings, fmt.Sprintf("%v: %d dots on the fold line", f, onLine))
+	}
+
+	return warnings
+}
+
+func (s *Sheet) Fold(f Fold) {
+	if f.Axis == 'x' {
+		s.FoldX(f.Line)
+	} else {
+		s.FoldY(f.Line)
+	}
+}
+
+// FoldStep is the sheet after a fold.
+type FoldStep struct {
+	Fold     Fold
+	Sheet    Sheet
+	NbDots   int
+	Warnings []string
+}
+
+// FoldAll returns the history of the folds, leaving s unchanged.
+func (s Sheet) FoldAll(folds []Fold) []FoldStep {
+	history := make([]FoldStep, 0, len(folds))
+
+	current := s.Clone()
+	for _, f := range folds {
+		warnings := current.Check(f)
+		current.Fold(f)
+		history = append(history, FoldStep{Fold: f, Sheet: current.Clone(), NbDots: current.NbDots(), Warnings: warnings})
+	}
+
+	return history
+}
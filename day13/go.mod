@@ -4,4 +4,13 @@ go 1.17
 
 replace github.com/gverger/advent2021/utils => ../utils
 
-require github.com/gverger/advent2021/utils v0.0.0-00010101000000-000000000000
+require (
+	github.com/gverger/advent2021/utils v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.7.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
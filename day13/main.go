@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,40 +11,49 @@ import (
 	"github.com/gverger/advent2021/utils/maps"
 )
 
+var strict = flag.Bool("strict", false, "fail when a letter of the code is not recognized")
+
 func main() {
 	utils.Main(run)
 }
 
 func run(lines []string) error {
-	dotLines := make([]string, 0)
-	for len(lines[0]) > 0 {
-		dotLines = append(dotLines, lines[0])
-		lines = lines[1:]
+	dotLines, foldLines, err := splitInput(lines)
+	if err != nil {
+		return err
 	}
 
-	lines = lines[1:]
-	foldLines := lines
+	folds, err := ParseFolds(foldLines)
+	if err != nil {
+		return err
+	}
 
-	s := NewSheetFromInput(dotLines)
+	s, err := NewSheetFromInput(dotLines)
+	if err != nil {
+		return err
+	}
+	history := s.FoldAll(folds)
 
-	fold(&s, []string{foldLines[0]})
-	fmt.Println("Nb Dots after one fold =", s.NbDots())
-	fold(&s, foldLines[1:])
-	fmt.Println(s)
+	for i, step := range history {
+		for _, w := range step.Warnings {
+			fmt.Println("Warning:", w)
+		}
+		fmt.Printf("Nb Dots after fold %d (%v) = %d\n", i+1, step.Fold, step.NbDots)
+	}
 
-	return nil
-}
+	final := history[len(history)-1].Sheet
+	fmt.Println(final)
 
-func fold(s *Sheet, lines []string) {
-	for _, l := range lines {
-		parts := strings.Split(l, "=")
-		value, _ := strconv.Atoi(parts[1])
-		if parts[0][len(parts[0])-1] == 'x' {
-			s.FoldX(value)
-		} else {
-			s.FoldY(value)
+	code, err := final.Read()
+	fmt.Println("Code =", code)
+	if err != nil {
+		if *strict {
+			return err
 		}
+		fmt.Println("Warning:", err)
 	}
+
+	return nil
 }
 
 type Sheet struct {
@@ -51,16 +62,49 @@ type Sheet struct {
 	maxY int
 }
 
-func NewSheetFromInput(lines []string) Sheet {
+// splitInput returns the dot lines and the fold lines, separated by an empty
+// line.
+func splitInput(lines []string) ([]string, []string, error) {
+	for i, l := range lines {
+		if len(l) == 0 {
+			if i+1 == len(lines) {
+				return nil, nil, errors.New("no fold after the empty line")
+			}
+			return lines[:i], lines[i+1:], nil
+		}
+	}
+
+	return nil, nil, errors.New("missing the empty line between the dots and the folds")
+}
+
+func NewSheetFromInput(lines []string) (Sheet, error) {
 	s := Sheet{dots: make(map[Position]bool)}
-	for _, l := range lines {
+	for i, l := range lines {
 		sCoords := strings.Split(l, ",")
+		if len(sCoords) != 2 {
+			return Sheet{}, fmt.Errorf("dot %d: %q: expected x,y", i+1, l)
+		}
 
-		coords, _ := maps.Strings(sCoords).ToInts()
+		coords, err := maps.Strings(sCoords).ToInts()
+		if err != nil {
+			return Sheet{}, fmt.Errorf("dot %d: %q: %w", i+1, l, err)
+		}
+		if coords[0] < 0 || coords[1] < 0 {
+			return Sheet{}, fmt.Errorf("dot %d: %q: negative coordinate", i+1, l)
+		}
 		s.AddDot(coords[0], coords[1])
 	}
 
-	return s
+	return s, nil
+}
+
+func (s Sheet) Clone() Sheet {
+	res := Sheet{dots: make(map[Position]bool, len(s.dots)), maxX: s.maxX, maxY: s.maxY}
+	for p := range s.dots {
+		res.dots[p] = true
+	}
+
+	return res
 }
 
 func (s Sheet) NbDots() int {
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// font is the expected bitmap of each letter, independent of glyphs.
+var font = map[rune][]string{
+	'A': {".##.", "#..#", "#..#", "####", "#..#", "#..#"},
+	'B': {"###.", "#..#", "###.", "#..#", "#..#", "###."},
+	'C': {".##.", "#..#", "#...", "#...", "#..#", ".##."},
+	'E': {"####", "#...", "###.", "#...", "#...", "####"},
+	'F': {"####", "#...", "###.", "#...", "#...", "#..."},
+	'G': {".##.", "#..#", "#...", "#.##", "#..#", ".###"},
+	'H': {"#..#", "#..#", "####", "#..#", "#..#", "#..#"},
+	'I': {".###", "..#.", "..#.", "..#.", "..#.", ".###"},
+	'J': {"..##", "...#", "...#", "...#", "#..#", ".##."},
+	'K': {"#..#", "#.#.", "##..", "#.#.", "#.#.", "#..#"},
+	'L': {"#...", "#...", "#...", "#...", "#...", "####"},
+	'O': {".##.", "#..#", "#..#", "#..#", "#..#", ".##."},
+	'P': {"###.", "#..#", "#..#", "###.", "#...", "#..."},
+	'R': {"###.", "#..#", "#..#", "###.", "#.#.", "#..#"},
+	'S': {".###", "#...", "#...", ".##.", "...#", "###."},
+	'U': {"#..#", "#..#", "#..#", "#..#", "#..#", ".##."},
+	'Z': {"####", "...#", "..#.", ".#..", "#...", "####"},
+}
+
+// art draws the text with the letters of font, separated by an empty column.
+func art(t *testing.T, text string) []string {
+	rows := make([]string, glyphHeight)
+	for i, letter := range text {
+		bitmap, ok := font[letter]
+		require.True(t, ok, "no bitmap for %c", letter)
+		for y := range rows {
+			if i > 0 {
+				rows[y] += "."
+			}
+			rows[y] += bitmap[y]
+		}
+	}
+
+	return rows
+}
+
+// sheetFromArt returns a sheet with a dot for each '#'.
+func sheetFromArt(rows []string) Sheet {
+	s := Sheet{dots: make(map[Position]bool)}
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				s.AddDot(x, y)
+			}
+		}
+	}
+	s.maxX = len(rows[0]) - 1
+	s.maxY = len(rows) - 1
+
+	return s
+}
+
+func TestRead(t *testing.T) {
+	for letter := range font {
+		t.Run(string(letter), func(t *testing.T) {
+			code, err := sheetFromArt(art(t, string(letter))).Read()
+			require.NoError(t, err)
+			require.Equal(t, string(letter), code)
+		})
+	}
+
+	for _, text := range []string{"PGHRKLKL", "ABCEFGHI", "JKLOPRSU", "ZZ"} {
+		t.Run(text, func(t *testing.T) {
+			code, err := sheetFromArt(art(t, text)).Read()
+			require.NoError(t, err)
+			require.Equal(t, text, code)
+		})
+	}
+}
+
+// unfold returns input lines of dots and folds that fold into the rows.
+// Every other dot is mirrored by each fold, so both halves of the sheet have
+// dots.
+func unfold(rows []string, folds []Fold) []string {
+	dots := make([]Position, 0)
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				dots = append(dots, Position{x: x, y: y})
+			}
+		}
+	}
+
+	for i := len(folds) - 1; i >= 0; i-- {
+		f := folds[i]
+		for j, p := range dots {
+			if j%2 == i%2 {
+				continue
+			}
+			if f.Axis == 'x' {
+				dots[j].x = 2*f.Line - p.x
+			} else {
+				dots[j].y = 2*f.Line - p.y
+			}
+		}
+	}
+
+	lines := make([]string, 0)
+	for _, p := range dots {
+		lines = append(lines, fmt.Sprintf("%d,%d", p.x, p.y))
+	}
+	lines = append(lines, "")
+	for _, f := range folds {
+		lines = append(lines, f.String())
+	}
+
+	return lines
+}
+func TestReadFoldedSheet(t *testing.T) {
+	folds := []Fold{{Axis: 'x', Line: 79}, {Axis: 'y', Line: 13}, {Axis: 'x', Line: 39}, {Axis: 'y', Line: 6}}
+	lines := unfold(art(t, "PGHRKLKL"), folds)
+	blank := len(lines) - len(folds) - 1
+
+	s, err := NewSheetFromInput(lines[:blank])
+	require.NoError(t, err)
+	require.Greater(t, s.maxX, 2*39)
+	parsed, err := ParseFolds(lines[blank+1:])
+	require.NoError(t, err)
+	require.Equal(t, folds, parsed)
+
+	history := s.FoldAll(parsed)
+	code, err := history[len(history)-1].Sheet.Read()
+	require.NoError(t, err)
+	require.Equal(t, "PGHRKLKL", code)
+}
+
+func TestReadUnknownGlyph(t *testing.T) {
+	s := sheetFromArt(art(t, "HELLO"))
+	s.AddDot(8, 2)
+
+	code, err := s.Read()
+	require.Equal(t, "H?LLO", code)
+
+	var unknown UnknownGlyphsError
+	require.ErrorAs(t, err, &unknown)
+	require.Equal(t, UnknownGlyphsError{{
+		Index:  1,
+		Bitmap: "####\n#...\n####\n#...\n#...\n####",
+	}}, unknown)
+}
+
+func TestParseFold(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected Fold
+		err      string
+	}{
+		{line: "fold along x=5", expected: Fold{Axis: 'x', Line: 5}},
+		{line: "fold along y=7", expected: Fold{Axis: 'y', Line: 7}},
+		{line: "fold along z=7", err: "unknown axis"},
+		{line: "fold along yx=7", err: "unknown axis"},
+		{line: "fold along y=", err: "invalid fold line"},
+		{line: "fold along y=a", err: "invalid fold line"},
+		{line: "fold along y=-1", err: "negative fold line"},
+		{line: "fold along y=1=2", err: "single '='"},
+		{line: "fold y=7", err: "expected"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			f, err := ParseFold(test.line)
+			if test.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, f)
+		})
+	}
+}
+
+func TestFoldAll(t *testing.T) {
+	content, err := os.ReadFile("test.txt")
+	require.NoError(t, err)
+	parts := strings.Split(strings.TrimSpace(string(content)), "\n\n")
+
+	s, err := NewSheetFromInput(strings.Split(parts[0], "\n"))
+	require.NoError(t, err)
+	folds, err := ParseFolds(strings.Split(parts[1], "\n"))
+	require.NoError(t, err)
+
+	history := s.FoldAll(folds)
+	require.Len(t, history, 2)
+	require.Equal(t, 17, history[0].NbDots)
+	require.Equal(t, 16, history[1].NbDots)
+	require.Empty(t, history[0].Warnings)
+	require.Empty(t, history[1].Warnings)
+	require.Equal(t, 17, history[0].Sheet.NbDots())
+	require.Equal(t, 18, s.NbDots())
+}
+
+func TestCheck(t *testing.T) {
+	s := Sheet{dots: make(map[Position]bool)}
+	s.AddDot(0, 0)
+	s.AddDot(2, 1)
+	s.AddDot(6, 2)
+
+	require.Empty(t, s.Check(Fold{Axis: 'x', Line: 3}))
+	require.Equal(t, []string{
+		"fold along x=2 is not at the middle of the sheet, from 0 to 6",
+		"fold along x=2: 1 dots on the fold line",
+	}, s.Check(Fold{Axis: 'x', Line: 2}))
+	require.Equal(t, []string{"fold along y=3 is not at the middle of the sheet, from 0 to 2"}, s.Check(Fold{Axis: 'y', Line: 3}))
+}
+
+func TestInputErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		err   string
+	}{
+		{name: "no empty line", lines: []string{"1,2", "fold along x=1"}, err: "missing the empty line"},
+		{name: "no fold", lines: []string{"1,2", ""}, err: "no fold"},
+		{name: "missing coordinate", lines: []string{"1", "", "fold along x=1"}, err: "dot 1"},
+		{name: "too many coordinates", lines: []string{"1,2", "1,2,3", "", "fold along x=1"}, err: "dot 2"},
+		{name: "not a number", lines: []string{"1,a", "", "fold along x=1"}, err: "dot 1"},
+		{name: "negative coordinate", lines: []string{"1,-2", "", "fold along x=1"}, err: "negative coordinate"},
+		{name: "bad fold", lines: []string{"1,2", "", "fold along x=?"}, err: "fold 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := run(test.lines)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.err)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Letters of the folded sheet are 4 dots wide and 6 dots high, with an empty
+// column between them.
+const (
+	glyphWidth   = 4
+	glyphHeight  = 6
+	glyphSpacing = 1
+)
+
+var glyphs = map[string]rune{
+	glyph(".##.", "#..#", "#..#", "####", "#..#", "#..#"): 'A',
+	glyph("###.", "#..#", "###.", "#..#", "#..#", "###."): 'B',
+	glyph(".##.", "#..#", "#...", "#...", "#..#", ".##."): 'C',
+	glyph("####", "#...", "###.", "#...", "#...", "####"): 'E',
+	glyph("####", "#...", "###.", "#...", "#...", "#..."): 'F',
+	glyph(".##.", "#..#", "#...", "#.##", "#..#", ".###"): 'G',
+	glyph("#..#", "#..#", "####", "#..#", "#..#", "#..#"): 'H',
+	glyph(".###", "..#.", "..#.", "..#.", "..#.", ".###"): 'I',
+	glyph("..##", "...#", "...#", "...#", "#..#", ".##."): 'J',
+	glyph("#..#", "#.#.", "##..", "#.#.", "#.#.", "#..#"): 'K',
+	glyph("#...", "#...", "#...", "#...", "#...", "####"): 'L',
+	glyph(".##.", "#..#", "#..#", "#..#", "#..#", ".##."): 'O',
+	glyph("###.", "#..#", "#..#", "###.", "#...", "#..."): 'P',
+	glyph("###.", "#..#", "#..#", "###.", "#.#.", "#..#"): 'R',
+	glyph(".###", "#...", "#...", ".##.", "...#", "###."): 'S',
+	glyph("#..#", "#..#", "#..#", "#..#", "#..#", ".##."): 'U',
+	glyph("####", "...#", "..#.", ".#..", "#...", "####"): 'Z',
+}
+
+func glyph(rows ...string) string {
+	return strings.Join(rows, "\n")
+}
+
+// UnknownGlyphError is returned when a letter of the sheet is not in the
+// font.
+type UnknownGlyphError struct {
+	// Index of the letter, starting at 0.
+	Index  int
+	Bitmap string
+}
+
+func (e UnknownGlyphError) Error() string {
+	return fmt.Sprintf("unknown glyph at letter %d:\n%s", e.Index, e.Bitmap)
+}
+
+// UnknownGlyphsError lists all the unknown letters of a sheet.
+type UnknownGlyphsError []UnknownGlyphError
+
+func (e UnknownGlyphsError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Read recognizes the capital letters written by the dots of the sheet. The
+// unknown letters are replaced by '?' and reported in the error.
+func (s Sheet) Read() (string, error) {
+	nbLetters := (s.maxX + 1 + glyphSpacing) / (glyphWidth + glyphSpacing)
+
+	var text strings.Builder
+	var unknown UnknownGlyphsError
+	for i := 0; i < nbLetters; i++ {
+		bitmap := s.bitmap(i * (glyphWidth + glyphSpacing))
+		letter, ok := glyphs[bitmap]
+		if !ok {
+			letter = '?'
+			unknown = append(unknown, UnknownGlyphError{Index: i, Bitmap: bitmap})
+		}
+		text.WriteRune(letter)
+	}
+
+	if len(unknown) > 0 {
+		return text.String(), unknown
+	}
+
+	return text.String(), nil
+}
+
+// bitmap returns the glyph starting at column x.
+func (s Sheet) bitmap(x int) string {
+	rows := make([]string, glyphHeight)
+	for y := range rows {
+		var row strings.Builder
+		for dx := 0; dx < glyphWidth; dx++ {
+			if s.dots[Position{x: x + dx, y: y}] {
+				row.WriteByte('#')
+			} else {
+				row.WriteByte('.')
+			}
+		}
+		rows[y] = row.String()
+	}
+
+	return glyph(rows...)
+}
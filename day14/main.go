@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"math/big"
 	"strings"
 
 	"github.com/gverger/advent2021/utils"
@@ -11,72 +13,87 @@ func main() {
 	utils.Main(run)
 }
 
+var (
+	part1Steps = flag.Int("part1-steps", 10, "number of steps of part 1")
+	part2Steps = flag.Int("part2-steps", 40, "number of steps of part 2")
+	table      = flag.Int("table", 0, "number of steps to print the element counts of")
+	forward    = flag.Int64("forward", 0, fmt.Sprintf("number of steps to compute the element counts after, with matrix exponentiation; -modulus is required above %d steps", maxExactSteps))
+	modulus    = flag.String("modulus", "", "modulus of the counts computed with -forward")
+)
+
 func run(lines []string) error {
+	stepFlags := []struct {
+		name string
+		nb   int
+	}{{"part1-steps", *part1Steps}, {"part2-steps", *part2Steps}, {"table", *table}}
+	for _, f := range stepFlags {
+		if f.nb < 0 {
+			return fmt.Errorf("-%s %d: the number of steps cannot be negative", f.name, f.nb)
+		}
+	}
+
 	p := ProblemFromInput(lines)
 
 	part1(p)
 	part2(p)
 
-	return nil
-}
-
-func part1(p Problem) {
-	fmt.Println("Part1: ", solve(p, 10))
-}
-
-func part2(p Problem) {
-	fmt.Println("Part2: ", solve(p, 40))
-}
-
-func solve(p Problem, nbSteps int) int {
-	polymerPairs := pairsFromTemplate(p.polymerTemplate)
+	if *table > 0 {
+		for step, counts := range p.Steps(*table) {
+			fmt.Printf("Step %d: %v\n", step, counts)
+		}
+	}
 
-	for i := 0; i < nbSteps; i++ {
-		polymerPairs = grow(polymerPairs, p.pairInsertions)
+	if *forward < 0 {
+		return fmt.Errorf("-forward %d: the number of steps cannot be negative", *forward)
+	}
+	if *forward > 0 {
+		m, err := parseModulus(*modulus)
+		if err != nil {
+			return err
+		}
+		if m == nil && *forward > maxExactSteps {
+			return fmt.Errorf("-forward %d needs a -modulus: exact counts have about as many bits as steps", *forward)
+		}
+
+		counts, err := p.CountsAfter(*forward, m)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Step %d: %v\n", *forward, counts)
 	}
 
-	return countMaxMinusMin(p.polymerTemplate, polymerPairs)
+	return nil
 }
 
-func countMaxMinusMin(template string, pairs map[Pair]int) int {
-	counts := make(map[byte]int)
-	counts[template[0]] += 1
-	counts[template[len(template)-1]] += 1
-	for p, nb := range pairs {
-		counts[p[0]] += nb
-		counts[p[1]] += nb
-	}
+// maxExactSteps is the highest number of steps computed without a modulus.
+const maxExactSteps = 1000
 
-	allCounts := make([]int, 0, len(counts))
-	for _, c := range counts {
-		allCounts = append(allCounts, c/2)
+// parseModulus returns nil when there is no modulus.
+func parseModulus(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
 	}
 
-	min, max := utils.MinMax(allCounts...)
+	m, ok := new(big.Int).SetString(s, 10)
+	if !ok || m.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid modulus %q: must be a positive integer", s)
+	}
 
-	return max - min
+	return m, nil
 }
 
-func pairsFromTemplate(template string) map[Pair]int {
-	pairs := make(map[Pair]int)
-
-	for i := 1; i < len(template); i++ {
-		pairs[Pair(template[i-1:i+1])]++
-	}
-
-	return pairs
+func part1(p Problem) {
+	fmt.Println("Part1: ", solve(p, *part1Steps))
 }
 
-func grow(polymer map[Pair]int, insertions map[Pair]rune) map[Pair]int {
-	res := make(map[Pair]int)
+func part2(p Problem) {
+	fmt.Println("Part2: ", solve(p, *part2Steps))
+}
 
-	for pair, nb := range polymer {
-		ins := insertions[pair]
-		res[Pair([]byte{pair[0], byte(ins)})] += nb
-		res[Pair([]byte{byte(ins), pair[1]})] += nb
-	}
+func solve(p Problem, nbSteps int) *big.Int {
+	steps := p.Steps(nbSteps)
 
-	return res
+	return steps[nbSteps].MaxMinusMin()
 }
 
 type Problem struct {
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func example(t *testing.T) Problem {
+	content, err := os.ReadFile("test.txt")
+	require.NoError(t, err)
+
+	return ProblemFromInput(strings.Split(strings.TrimSpace(string(content)), "\n"))
+}
+
+func TestSolve(t *testing.T) {
+	p := example(t)
+
+	require.Equal(t, big.NewInt(1588), solve(p, 10))
+	require.Equal(t, "2188189693529", solve(p, 40).String())
+}
+
+func TestSteps(t *testing.T) {
+	steps := example(t).Steps(2)
+
+	require.Len(t, steps, 3)
+	require.Equal(t, "B=1 C=1 N=2", steps[0].String())
+	require.Equal(t, "B=2 C=2 H=1 N=2", steps[1].String())
+	require.Equal(t, "B=6 C=4 H=1 N=2", steps[2].String())
+}
+
+func TestPairsWithoutRules(t *testing.T) {
+	p := ProblemFromInput([]string{"NNCB", "", "NN -> C"})
+
+	steps := p.Steps(2)
+	require.Equal(t, "B=1 C=1 N=2", steps[0].String())
+	require.Equal(t, "B=1 C=2 N=2", steps[1].String())
+	require.Equal(t, "B=1 C=2 N=2", steps[2].String())
+}
+
+func TestCountsAfter(t *testing.T) {
+	p := example(t)
+	steps := p.Steps(20)
+
+	for n, counts := range steps {
+		after, err := p.CountsAfter(int64(n), nil)
+		require.NoError(t, err)
+		require.Equal(t, counts.String(), after.String(), "step %d", n)
+	}
+
+	modulus := big.NewInt(1000)
+	after, err := p.CountsAfter(40, modulus)
+	require.NoError(t, err)
+	require.Equal(t, "B=602 C=301 H=73 N=353", after.String())
+}
+
+func TestForwardUnknownPair(t *testing.T) {
+	p := example(t)
+	tr := NewTransition(NewPolymer(p.polymerTemplate), p.pairInsertions, nil)
+
+	_, err := tr.Forward(NewPolymer("NNXY"), 3)
+	require.Error(t, err)
+
+	// pairs without rules are kept as they are
+	polymer := NewPolymer("NNXY")
+	tr = NewTransition(polymer, p.pairInsertions, nil)
+	after, err := tr.Forward(polymer, 10)
+	require.NoError(t, err)
+
+	expected := polymer
+	for i := 0; i < 10; i++ {
+		expected = expected.Grow(p.pairInsertions)
+	}
+	require.Equal(t, expected.Counts().String(), after.Counts().String())
+}
+
+func TestNegativeSteps(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace("NNCB\n\nNN -> C"), "\n")
+
+	for _, steps := range []*int{part1Steps, part2Steps, table} {
+		previous := *steps
+		*steps = -1
+		err := run(lines)
+		*steps = previous
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cannot be negative")
+	}
+}
+
+func TestCountsAfterKeepsZeroCounts(t *testing.T) {
+	p := example(t)
+
+	after, err := p.CountsAfter(2, big.NewInt(2))
+	require.NoError(t, err)
+	require.Equal(t, "B=0 C=0 H=1 N=0", after.String())
+
+	// H is only inserted at step 1
+	after, err = p.CountsAfter(0, big.NewInt(2))
+	require.NoError(t, err)
+	require.Equal(t, "B=1 C=1 N=0", after.String())
+}
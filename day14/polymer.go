@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Polymer is a chain of elements, stored as the number of each pair of
+// adjacent elements. Each element except the first and last one is in two
+// pairs.
+type Polymer struct {
+	first byte
+	last  byte
+	pairs map[Pair]*big.Int
+}
+
+func NewPolymer(template string) Polymer {
+	p := Polymer{first: template[0], last: template[len(template)-1], pairs: make(map[Pair]*big.Int)}
+
+	for i := 1; i < len(template); i++ {
+		p.add(Pair(template[i-1:i+1]), big.NewInt(1))
+	}
+
+	return p
+}
+
+func (p Polymer) add(pair Pair, nb *big.Int) {
+	if current, ok := p.pairs[pair]; ok {
+		current.Add(current, nb)
+		return
+	}
+
+	p.pairs[pair] = new(big.Int).Set(nb)
+}
+
+// Pairs returns the sorted pairs of the polymer.
+func (p Polymer) Pairs() []Pair {
+	res := make([]Pair, 0, len(p.pairs))
+	for pair := range p.pairs {
+		res = append(res, pair)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
+
+// Grow inserts elements between the pairs with a rule. The other pairs are
+// left untouched.
+func (p Polymer) Grow(insertions map[Pair]rune) Polymer {
+	res := Polymer{first: p.first, last: p.last, pairs: make(map[Pair]*big.Int)}
+
+	for pair, nb := range p.pairs {
+		ins, ok := insertions[pair]
+		if !ok {
+			res.add(pair, nb)
+			continue
+		}
+
+		res.add(Pair([]byte{pair[0], byte(ins)}), nb)
+		res.add(Pair([]byte{byte(ins), pair[1]}), nb)
+	}
+
+	return res
+}
+
+// Counts returns the number of each element of the polymer. Every element is
+// the first one of a pair, except the last element.
+func (p Polymer) Counts() Counts {
+	res := make(Counts)
+	res.add(p.last, big.NewInt(1))
+	for pair, nb := range p.pairs {
+		res.add(pair[0], nb)
+	}
+
+	return res
+}
+
+// Counts are the number of each element of a polymer.
+type Counts map[byte]*big.Int
+
+func (c Counts) add(element byte, nb *big.Int) {
+	if current, ok := c[element]; ok {
+		current.Add(current, nb)
+		return
+	}
+
+	c[element] = new(big.Int).Set(nb)
+}
+
+// MaxMinusMin returns the difference between the most and least common
+// elements.
+func (c Counts) MaxMinusMin() *big.Int {
+	var min, max *big.Int
+	for _, nb := range c {
+		if min == nil || nb.Cmp(min) < 0 {
+			min = nb
+		}
+		if max == nil || nb.Cmp(max) > 0 {
+			max = nb
+		}
+	}
+
+	return new(big.Int).Sub(max, min)
+}
+
+func (c Counts) Elements() []byte {
+	res := make([]byte, 0, len(c))
+	for e := range c {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
+
+func (c Counts) String() string {
+	parts := make([]string, 0, len(c))
+	for _, e := range c.Elements() {
+		parts = append(parts, fmt.Sprintf("%c=%s", e, c[e]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Steps returns the counts of the elements of the polymer at each step, from
+// the template at step 0 to nbSteps.
+func (p Problem) Steps(nbSteps int) []Counts {
+	polymer := NewPolymer(p.polymerTemplate)
+	res := []Counts{polymer.Counts()}
+
+	for i := 0; i < nbSteps; i++ {
+		polymer = polymer.Grow(p.pairInsertions)
+		res = append(res, polymer.Counts())
+	}
+
+	return res
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// Transition is a step of the pair insertion as a matrix over the pair types:
+// after a step, there are matrix[to][from] pairs `to` for each pair `from`.
+// The counts are reduced modulo modulus, unless it is nil.
+type Transition struct {
+	pairs   []Pair
+	index   map[Pair]int
+	matrix  [][]*big.Int
+	modulus *big.Int
+	// appears is the first step at which each element is in the polymer.
+	appears map[byte]int
+}
+
+// NewTransition builds the transition of the pairs reachable from the pairs
+// of the polymer.
+func NewTransition(p Polymer, insertions map[Pair]rune, modulus *big.Int) Transition {
+	t := Transition{index: make(map[Pair]int), modulus: modulus, appears: map[byte]int{p.first: 0, p.last: 0}}
+	// the pairs are added in breadth first order, so a pair is first in the
+	// polymer at the step of its depth
+	depths := make([]int, 0)
+	add := func(pair Pair, depth int) {
+		if _, ok := t.index[pair]; ok {
+			return
+		}
+		t.index[pair] = len(t.pairs)
+		t.pairs = append(t.pairs, pair)
+		depths = append(depths, depth)
+		for _, element := range []byte{pair[0], pair[1]} {
+			if step, ok := t.appears[element]; !ok || depth < step {
+				t.appears[element] = depth
+			}
+		}
+	}
+
+	for _, pair := range p.Pairs() {
+		add(pair, 0)
+	}
+	for i := 0; i < len(t.pairs); i++ {
+		for _, next := range produced(t.pairs[i], insertions) {
+			add(next, depths[i]+1)
+		}
+	}
+
+	t.matrix = t.zero()
+	for from, pair := range t.pairs {
+		for _, next := range produced(pair, insertions) {
+			to := t.index[next]
+			t.matrix[to][from].Add(t.matrix[to][from], big.NewInt(1))
+		}
+	}
+	t.reduce(t.matrix)
+
+	return t
+}
+
+// produced returns the pairs replacing pair after a step.
+func produced(pair Pair, insertions map[Pair]rune) []Pair {
+	ins, ok := insertions[pair]
+	if !ok {
+		return []Pair{pair}
+	}
+
+	return []Pair{Pair([]byte{pair[0], byte(ins)}), Pair([]byte{byte(ins), pair[1]})}
+}
+
+func (t Transition) Pairs() []Pair {
+	res := make([]Pair, len(t.pairs))
+	copy(res, t.pairs)
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
+
+func (t Transition) zero() [][]*big.Int {
+	res := make([][]*big.Int, len(t.pairs))
+	for i := range res {
+		res[i] = make([]*big.Int, len(t.pairs))
+		for j := range res[i] {
+			res[i][j] = new(big.Int)
+		}
+	}
+
+	return res
+}
+
+func (t Transition) reduce(m [][]*big.Int) {
+	if t.modulus == nil {
+		return
+	}
+
+	for _, row := range m {
+		for _, v := range row {
+			v.Mod(v, t.modulus)
+		}
+	}
+}
+
+func (t Transition) mul(a, b [][]*big.Int) [][]*big.Int {
+	res := t.zero()
+	prod := new(big.Int)
+	for i := range a {
+		for k := range b {
+			if a[i][k].Sign() == 0 {
+				continue
+			}
+			for j := range b[k] {
+				res[i][j].Add(res[i][j], prod.Mul(a[i][k], b[k][j]))
+			}
+		}
+	}
+	t.reduce(res)
+
+	return res
+}
+
+func (t Transition) apply(m [][]*big.Int, v []*big.Int) []*big.Int {
+	res := make([]*big.Int, len(v))
+	prod := new(big.Int)
+	for i := range m {
+		res[i] = new(big.Int)
+		for j := range v {
+			res[i].Add(res[i], prod.Mul(m[i][j], v[j]))
+		}
+		if t.modulus != nil {
+			res[i].Mod(res[i], t.modulus)
+		}
+	}
+
+	return res
+}
+
+// Forward returns the polymer after nbSteps steps, squaring the transition
+// matrix so that it takes O(P³ log(nbSteps)) with P pair types. The pairs of
+// the polymer must be in the transition.
+func (t Transition) Forward(p Polymer, nbSteps int64) (Polymer, error) {
+	v := make([]*big.Int, len(t.pairs))
+	for i := range v {
+		v[i] = new(big.Int)
+	}
+	for pair, nb := range p.pairs {
+		i, ok := t.index[pair]
+		if !ok {
+			return Polymer{}, fmt.Errorf("pair %s is not in the transition", pair)
+		}
+		v[i].Set(nb)
+	}
+	t.reduce([][]*big.Int{v})
+
+	power := t.matrix
+	for n := nbSteps; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			v = t.apply(power, v)
+		}
+		if n > 1 {
+			power = t.mul(power, power)
+		}
+	}
+
+	res := Polymer{first: p.first, last: p.last, pairs: make(map[Pair]*big.Int)}
+	for i, nb := range v {
+		if nb.Sign() != 0 {
+			res.pairs[t.pairs[i]] = nb
+		}
+	}
+
+	return res, nil
+}
+
+// CountsAfter returns the counts of the elements after nbSteps steps, modulo
+// modulus unless it is nil.
+func (p Problem) CountsAfter(nbSteps int64, modulus *big.Int) (Counts, error) {
+	polymer := NewPolymer(p.polymerTemplate)
+	t := NewTransition(polymer, p.pairInsertions, modulus)
+
+	polymer, err := t.Forward(polymer, nbSteps)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := polymer.Counts()
+	if modulus != nil {
+		for _, nb := range counts {
+			nb.Mod(nb, modulus)
+		}
+	}
+
+	// the counts of some elements may be 0 modulo modulus, they are still in
+	// the polymer
+	for element, step := range t.appears {
+		if int64(step) <= nbSteps {
+			counts.add(element, new(big.Int))
+		}
+	}
+
+	return counts, nil
+}
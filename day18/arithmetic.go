@@ -0,0 +1,191 @@
+package main
+
+import "fmt"
+
+// Arithmetic holds the rules used to reduce snailfish numbers and compute their
+// magnitude.
+type Arithmetic struct {
+	// ExplodeDepth is the depth at which pairs explode, a pair nested in 4
+	// pairs being at depth 5.
+	ExplodeDepth int
+	// SplitThreshold is the lowest value that splits.
+	SplitThreshold int
+
+	// LeftWeight and RightWeight multiply the magnitudes of the elements of a
+	// pair.
+	LeftWeight  int
+	RightWeight int
+}
+
+// Default is the arithmetic of the puzzle.
+var Default = Arithmetic{ExplodeDepth: 5, SplitThreshold: 10, LeftWeight: 3, RightWeight: 2}
+
+// Validate returns an error when the rules cannot reduce numbers: an explode
+// depth below 2 would explode the number itself, a split threshold below 2
+// would split forever, and a weight must be positive to compute magnitudes.
+func (a Arithmetic) Validate() error {
+	if a.ExplodeDepth < 2 {
+		return fmt.Errorf("explode depth %d must be at least 2", a.ExplodeDepth)
+	}
+	if a.SplitThreshold < 2 {
+		return fmt.Errorf("split threshold %d must be at least 2", a.SplitThreshold)
+	}
+	if a.LeftWeight <= 0 || a.RightWeight <= 0 {
+		return fmt.Errorf("weights %d and %d must be positive", a.LeftWeight, a.RightWeight)
+	}
+
+	return nil
+}
+
+// ParseArray parses a number reduced with this arithmetic.
+func (a Arithmetic) ParseArray(line string) (FlatNumber, error) {
+	return parseArray(line, a.ExplodeDepth-1)
+}
+
+func (a Arithmetic) Add(n1, n2 FlatNumber) FlatNumber {
+	res := make(FlatNumber, 0, len(n1)+len(n2)+2)
+
+	return addInto(res, n1, n2)
+}
+
+// Sum adds all the numbers in order, and returns the magnitude of the result.
+func (a Arithmetic) Sum(numbers []FlatNumber) int {
+	res := numbers[0]
+	for _, n := range numbers[1:] {
+		res = a.Add(res, n)
+		trace.Println("after addition:", res)
+		res = a.TraceReduce(res, traceReduction)
+	}
+
+	return a.Magnitude(res)
+}
+
+func (a Arithmetic) Reduce(n FlatNumber) FlatNumber {
+	return a.TraceReduce(n, nil)
+}
+
+// TraceReduce reduces the number, calling onStep after each step when not nil.
+func (a Arithmetic) TraceReduce(n FlatNumber, onStep func(Reduction)) FlatNumber {
+	return a.reduceInPlace(n.Copy(), onStep)
+}
+
+func (a Arithmetic) Explode(n FlatNumber) (FlatNumber, bool) {
+	idx := n.FirstAtDepth(a.ExplodeDepth)
+	if idx == -1 {
+		return n.Copy(), false
+	}
+
+	res, _ := a.explodeInPlace(n.Copy(), idx)
+
+	return res, true
+}
+
+func (a Arithmetic) Split(n FlatNumber) (FlatNumber, bool) {
+	idx := n.FirstValueAtLeast(a.SplitThreshold)
+	if idx == -1 {
+		return n.Copy(), false
+	}
+
+	res, _ := splitInPlace(n.Copy(), idx)
+
+	return res, true
+}
+
+func (a Arithmetic) Magnitude(n FlatNumber) int {
+	multiplier := 1
+	magnitude := 0
+
+	needComma := false
+	for _, p := range n {
+		switch p {
+		case Open:
+			multiplier = multiplier * a.LeftWeight
+			if needComma {
+				multiplier = multiplier / a.LeftWeight
+				multiplier = multiplier * a.RightWeight
+				needComma = false
+			}
+		case Close:
+			multiplier = multiplier / a.RightWeight
+			needComma = true
+		default:
+			if needComma {
+				multiplier = multiplier / a.LeftWeight
+				multiplier = multiplier * a.RightWeight
+			}
+			magnitude += multiplier * p
+			needComma = true
+		}
+	}
+
+	return magnitude
+}
+
+// reduceInPlace reduces n in its own backing array, which only grows when a
+// split needs more room.
+func (a Arithmetic) reduceInPlace(n FlatNumber, onStep func(Reduction)) FlatNumber {
+	for {
+		var step Reduction
+		if idx := n.FirstAtDepth(a.ExplodeDepth); idx != -1 {
+			n, step = a.explodeInPlace(n, idx)
+		} else if idx := n.FirstValueAtLeast(a.SplitThreshold); idx != -1 {
+			n, step = splitInPlace(n, idx)
+		} else {
+			return n
+		}
+
+		if onStep != nil {
+			step.Result = n.Copy()
+			onStep(step)
+		}
+	}
+}
+
+// explodeInPlace explodes the pair at idx.
+func (a Arithmetic) explodeInPlace(n FlatNumber, idx int) (FlatNumber, Reduction) {
+	leftNumber := n[idx+1]
+	rightNumber := n[idx+2]
+	step := Reduction{
+		Action:     ActionExplode,
+		Index:      idx,
+		Depth:      a.ExplodeDepth,
+		LeftValue:  leftNumber,
+		RightValue: rightNumber,
+		LeftIndex:  -1,
+		RightIndex: -1,
+	}
+
+	for i := idx - 1; i >= 0; i-- {
+		if IsValue(n[i]) {
+			n[i] += leftNumber
+			step.LeftIndex = i
+			break
+		}
+	}
+	for i := idx + 4; i < len(n); i++ {
+		if IsValue(n[i]) {
+			n[i] += rightNumber
+			step.RightIndex = i
+			break
+		}
+	}
+
+	n[idx] = 0
+	copy(n[idx+1:], n[idx+4:])
+
+	return n[:len(n)-3], step
+}
+
+// splitInPlace splits the value at idx.
+func splitInPlace(n FlatNumber, idx int) (FlatNumber, Reduction) {
+	value := n[idx]
+
+	n = append(n, 0, 0, 0)
+	copy(n[idx+4:], n[idx+1:len(n)-3])
+	n[idx] = Open
+	n[idx+1] = value / 2
+	n[idx+2] = (value + 1) / 2
+	n[idx+3] = Close
+
+	return n, Reduction{Action: ActionSplit, Index: idx, Value: value}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Best is the largest magnitude of the sum of two different numbers, I and J
+// being their indices.
+type Best struct {
+	I         int
+	J         int
+	Magnitude int
+}
+
+// noBest is the result when there are less than two numbers.
+var noBest = Best{I: -1, J: -1}
+
+// better tells whether b beats other, the smallest indices winning ties so the
+// result does not depend on the order of the workers. Any pair beats noBest.
+func (b Best) better(other Best) bool {
+	if b == noBest || other == noBest {
+		return other == noBest && b != noBest
+	}
+	if b.Magnitude != other.Magnitude {
+		return b.Magnitude > other.Magnitude
+	}
+	if b.I != other.I {
+		return b.I < other.I
+	}
+
+	return b.J < other.J
+}
+
+// LargestMagnitude tries every ordered pair of numbers on a pool of workers,
+// runtime.NumCPU() of them when workers is not positive.
+func (a Arithmetic) LargestMagnitude(numbers []FlatNumber, workers int) Best {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	rows := make(chan int)
+	results := make(chan Best, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- a.bestOfRows(numbers, rows)
+		}()
+	}
+
+	go func() {
+		for i := range numbers {
+			rows <- i
+		}
+		close(rows)
+		wg.Wait()
+		close(results)
+	}()
+
+	best := noBest
+	for b := range results {
+		if b.better(best) {
+			best = b
+		}
+	}
+
+	return best
+}
+
+// bestOfRows returns the best sum of numbers[i] with any other number, for
+// every i received, reusing the same buffer for all the sums.
+func (a Arithmetic) bestOfRows(numbers []FlatNumber, rows <-chan int) Best {
+	best := noBest
+	buffer := make(FlatNumber, 0)
+
+	for i := range rows {
+		for j := range numbers {
+			if i == j {
+				continue
+			}
+
+			buffer = addInto(buffer[:0], numbers[i], numbers[j])
+			buffer = a.reduceInPlace(buffer, nil)
+
+			current := Best{I: i, J: j, Magnitude: a.Magnitude(buffer)}
+			if current.better(best) {
+				best = current
+			}
+		}
+	}
+
+	return best
+}
+
+// addInto appends the sum of n1 and n2 to res.
+func addInto(res, n1, n2 FlatNumber) FlatNumber {
+	res = append(res, Open)
+	res = append(res, n1...)
+	res = append(res, n2...)
+
+	return append(res, Close)
+}
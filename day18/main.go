@@ -1,62 +1,78 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/gverger/advent2021/utils"
+	"github.com/gverger/advent2021/utils/debug"
 )
 
+var trace = debug.Trace{On: false}
+
+var arithmetic = Default
+
 func main() {
+	flag.BoolVar(&trace.On, "trace", false, "print every reduction step")
+	flag.IntVar(&arithmetic.ExplodeDepth, "explode-depth", Default.ExplodeDepth, "depth of the pairs that explode")
+	flag.IntVar(&arithmetic.SplitThreshold, "split-threshold", Default.SplitThreshold, "lowest value that splits")
+	flag.IntVar(&arithmetic.LeftWeight, "left-weight", Default.LeftWeight, "magnitude weight of the left elements")
+	flag.IntVar(&arithmetic.RightWeight, "right-weight", Default.RightWeight, "magnitude weight of the right elements")
+
 	utils.Main(run)
 }
 
 func run(lines []string) error {
-	numbers := parseNumbers(lines)
+	if err := arithmetic.Validate(); err != nil {
+		return err
+	}
 
-	fmt.Println("Magnitude of the whole sum:", Part1(numbers))
-	fmt.Println("Largest Magnitude", Part2(numbers))
+	numbers, err := arithmetic.parseNumbers(lines)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Magnitude of the whole sum:", arithmetic.Sum(numbers))
+	best := arithmetic.LargestMagnitude(numbers, 0)
+	if best == noBest {
+		return fmt.Errorf("the largest magnitude needs at least two numbers")
+	}
+	fmt.Printf("Largest Magnitude %d (numbers %d and %d)\n", best.Magnitude, best.I+1, best.J+1)
 
 	return nil
 }
 
 func Part1(numbers []FlatNumber) int {
-	res := numbers[0]
-	for _, n := range numbers[1:] {
-		res = AddArray(res, n)
-		res = ReduceArray(res)
-	}
-
-	return res.Magnitude()
+	return Default.Sum(numbers)
 }
 
-func Part2(numbers []FlatNumber) int {
-	max := 0
+func traceReduction(r Reduction) {
+	trace.Println(r)
+	trace.Println("  ", r.Details())
+}
 
-	for i, n1 := range numbers {
-		for j, n2 := range numbers {
-			if i == j {
-				continue
-			}
-			mag := ReduceArray(AddArray(n1, n2)).Magnitude()
+func Part2(numbers []FlatNumber) Best {
+	return Default.LargestMagnitude(numbers, 0)
+}
 
-			if mag > max {
-				max = mag
-			}
+func (a Arithmetic) parseNumbers(lines []string) ([]FlatNumber, error) {
+	res := make([]FlatNumber, 0, len(lines))
+	errs := make(ParseErrors, 0)
+	for i, l := range lines {
+		n, err := a.ParseArray(l)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", i+1, err))
+			continue
 		}
+		res = append(res, n)
 	}
 
-	return max
-}
-
-func parseNumbers(lines []string) []FlatNumber {
-	res := make([]FlatNumber, 0, len(lines))
-	for _, l := range lines {
-		res = append(res, ParseArray(l))
+	if len(errs) > 0 {
+		return nil, errs
 	}
 
-	return res
+	return res, nil
 }
 
 const (
@@ -64,75 +80,17 @@ const (
 	Close = -2
 )
 
+// FlatNumber is a snailfish number as a list of values and Open/Close
+// brackets. The arithmetic functions never modify their inputs, and always
+// return a number sharing no memory with them.
 type FlatNumber []int
 
 func IsValue(n int) bool {
 	return n >= 0
 }
 
-func ParseArray(line string) FlatNumber {
-	res := make([]int, 0)
-
-	current := 0
-	parsingValue := false
-	for _, c := range line {
-		switch c {
-		case '[':
-			res = append(res, Open)
-		case ']':
-			if parsingValue {
-				res = append(res, current)
-				current = 0
-				parsingValue = false
-			}
-			res = append(res, Close)
-		case ',':
-			if parsingValue {
-				res = append(res, current)
-				current = 0
-				parsingValue = false
-			}
-		default:
-			parsingValue = true
-			n, e := strconv.Atoi(string(c))
-			if e != nil {
-				panic(e)
-			}
-			current = current*10 + n
-		}
-	}
-
-	return res
-}
-
 func (n FlatNumber) Magnitude() int {
-	multiplier := 1
-	magnitude := 0
-
-	needComma := false
-	for _, p := range n {
-		switch p {
-		case Open:
-			multiplier = multiplier * 3
-			if needComma {
-				multiplier = multiplier / 3
-				multiplier = multiplier * 2
-				needComma = false
-			}
-		case Close:
-			multiplier = multiplier / 2
-			needComma = true
-		default:
-			if needComma {
-				multiplier = multiplier / 3
-				multiplier = multiplier * 2
-			}
-			magnitude += multiplier * p
-			needComma = true
-		}
-	}
-
-	return magnitude
+	return Default.Magnitude(n)
 }
 
 func (n FlatNumber) String() string {
@@ -162,74 +120,33 @@ func (n FlatNumber) String() string {
 	return b.String()
 }
 
-func AddArray(n1, n2 FlatNumber) FlatNumber {
-	res := make(FlatNumber, 0)
-
-	res = append(res, Open)
-	res = append(res, n1...)
-	res = append(res, n2...)
-	res = append(res, Close)
+func (n FlatNumber) Copy() FlatNumber {
+	res := make(FlatNumber, len(n))
+	copy(res, n)
 
 	return res
 }
 
-func ReduceArray(n FlatNumber) FlatNumber {
-	res, changed := Explode(n)
+func AddArray(n1, n2 FlatNumber) FlatNumber {
+	return Default.Add(n1, n2)
+}
 
-	for changed {
-		for changed {
-			res, changed = Explode(res)
-		}
-		res, changed = Split(res)
-	}
+func ReduceArray(n FlatNumber) FlatNumber {
+	return Default.Reduce(n)
+}
 
-	return res
+// TraceReduceArray reduces the number, calling onStep after each step when not
+// nil.
+func TraceReduceArray(n FlatNumber, onStep func(Reduction)) FlatNumber {
+	return Default.TraceReduce(n, onStep)
 }
 
 func Explode(n FlatNumber) (FlatNumber, bool) {
-	nestedPairIdx := n.FirstAtDepth(5)
-	if nestedPairIdx == -1 {
-		return n, false
-	}
-
-	leftNumber := n[nestedPairIdx+1]
-	rightNumber := n[nestedPairIdx+2]
-
-	for i := nestedPairIdx - 1; i >= 0; i-- {
-		if IsValue(n[i]) {
-			n[i] += leftNumber
-			break
-		}
-	}
-	for i := nestedPairIdx + 4; i < len(n); i++ {
-		if IsValue(n[i]) {
-			n[i] += rightNumber
-			break
-		}
-	}
-
-	res := make(FlatNumber, len(n)-3)
-	copy(res, n[:nestedPairIdx])
-	copy(res[nestedPairIdx+1:], n[nestedPairIdx+4:])
-
-	return res, true
+	return Default.Explode(n)
 }
 
 func Split(n FlatNumber) (FlatNumber, bool) {
-	idx := n.FirstValueTooHigh()
-	if idx == -1 {
-		return n, false
-	}
-
-	res := make(FlatNumber, len(n)+3)
-	copy(res, n[:idx])
-	copy(res[idx+4:], n[idx+1:])
-	res[idx] = Open
-	res[idx+1] = n[idx] / 2
-	res[idx+2] = (n[idx] + 1) / 2
-	res[idx+3] = Close
-
-	return res, true
+	return Default.Split(n)
 }
 
 func (n FlatNumber) FirstAtDepth(d int) int {
@@ -251,11 +168,15 @@ func (n FlatNumber) FirstAtDepth(d int) int {
 }
 
 func (n FlatNumber) FirstValueTooHigh() int {
+	return n.FirstValueAtLeast(Default.SplitThreshold)
+}
+
+func (n FlatNumber) FirstValueAtLeast(threshold int) int {
 	for i, c := range n {
 		if !IsValue(c) {
 			continue
 		}
-		if c >= 10 {
+		if c >= threshold {
 			return i
 		}
 	}
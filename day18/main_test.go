@@ -1,8 +1,10 @@
 package main
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/gverger/advent2021/utils"
 	"github.com/stretchr/testify/require"
 )
 
@@ -22,7 +24,8 @@ func TestParseLine(t *testing.T) {
 
 	for _, test := range testCases {
 		t.Run(test.line, func(t *testing.T) {
-			parsed := ParseLine(test.line)
+			parsed, err := ParseLine(test.line)
+			require.NoError(t, err)
 
 			require.Equal(t, test.line, parsed.String())
 		})
@@ -42,13 +45,45 @@ func TestParseArray(t *testing.T) {
 
 	for _, test := range testCases {
 		t.Run(test.line, func(t *testing.T) {
-			parsed := ParseArray(test.line)
+			parsed, err := ParseArray(test.line)
+			require.NoError(t, err)
 
 			require.Equal(t, test.output, parsed)
 		})
 	}
 }
 
+func TestParseArrayErrors(t *testing.T) {
+	testCases := []struct {
+		line string
+		err  string
+	}{
+		{line: "5", err: "column 1: a number must be a pair, got '5'"},
+		{line: "[1,2", err: "column 5: unbalanced brackets: missing ']'"},
+		{line: "[1,2]]", err: "column 6: unbalanced brackets: unexpected ']'"},
+		{line: "[[1,2]3]", err: "column 7: missing comma, got '3'"},
+		{line: "[1]", err: "column 3: wrong arity: pair with a single element"},
+		{line: "[1,2,3]", err: "column 5: wrong arity: pair with more than two elements"},
+		{line: "[]", err: "column 2: wrong arity: empty pair"},
+		{line: "[1,x]", err: "column 4: expected a value or a pair, got 'x'"},
+		{line: "[[[[[9,8],1],2],3],4]", err: "column 5: pair nested at depth 5, more than 4"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.line, func(t *testing.T) {
+			_, err := ParseArray(test.line)
+
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestParseNumbersErrors(t *testing.T) {
+	_, err := Default.parseNumbers([]string{"[1,2]", "[1", "[3,4]", "[1,2,3]"})
+
+	require.EqualError(t, err, "line 2: column 3: unbalanced brackets: missing ']'\nline 4: column 5: wrong arity: pair with more than two elements")
+}
+
 func TestAddArray(t *testing.T) {
 	tests := []struct {
 		n1     string
@@ -63,8 +98,8 @@ func TestAddArray(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.output, func(t *testing.T) {
-			n1 := ParseArray(test.n1)
-			n2 := ParseArray(test.n2)
+			n1 := parse(t, test.n1)
+			n2 := parse(t, test.n2)
 
 			require.Equal(t, test.output, AddArray(n1, n2).String())
 		})
@@ -106,8 +141,8 @@ func TestExplode(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.number, func(t *testing.T) {
-			n := ParseArray(test.number)
-			expected := ParseArray(test.output)
+			n := parse(t, test.number)
+			expected := parse(t, test.output)
 
 			exploded, isModified := Explode(n)
 			require.Equal(t, !test.same, isModified)
@@ -131,8 +166,8 @@ func TestSplit(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.number, func(t *testing.T) {
-			n := ParseArray(test.number)
-			expected := ParseArray(test.output)
+			n := parse(t, test.number)
+			expected := parse(t, test.output)
 
 			exploded, isModified := Split(n)
 			require.Equal(t, !test.same, isModified)
@@ -155,8 +190,8 @@ func TestReduce(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.number, func(t *testing.T) {
-			n := ParseArray(test.number)
-			expected := ParseArray(test.output)
+			n := parse(t, test.number)
+			expected := parse(t, test.output)
 
 			exploded := ReduceArray(n)
 
@@ -190,9 +225,305 @@ func TestMagnitude(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.number, func(t *testing.T) {
-			n := ParseArray(test.number)
+			n := parse(t, test.number)
 
 			require.Equal(t, test.result, n.Magnitude())
 		})
 	}
 }
+
+func TestTraceReduce(t *testing.T) {
+	steps := make([]string, 0)
+	reduced := TraceReduceArray(parse(t, "[[[[[4,3],4],4],[7,[[8,4],9]]],[1,1]]"), func(r Reduction) {
+		steps = append(steps, r.String())
+	})
+
+	require.Equal(t, "[[[[0,7],4],[[7,8],[6,0]]],[8,1]]", reduced.String())
+	require.Equal(t, []string{
+		"after explode:  [[[[0,7],4],[7,[[8,4],9]]],[1,1]]",
+		"after explode:  [[[[0,7],4],[15,[0,13]]],[1,1]]",
+		"after split:    [[[[0,7],4],[[7,8],[0,13]]],[1,1]]",
+		"after split:    [[[[0,7],4],[[7,8],[0,[6,7]]]],[1,1]]",
+		"after explode:  [[[[0,7],4],[[7,8],[6,0]]],[8,1]]",
+	}, steps)
+	for _, step := range steps {
+		require.Equal(t, len("after addition: "), strings.Index(step, "["), step)
+	}
+}
+
+func parse(t *testing.T, line string) FlatNumber {
+	n, err := parseArray(line, 0)
+	require.NoError(t, err)
+
+	return n
+}
+
+func TestLargestMagnitude(t *testing.T) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+	numbers, err := Default.parseNumbers(lines)
+	require.NoError(t, err)
+
+	for _, workers := range []int{1, 3, 0} {
+		best := Default.LargestMagnitude(numbers, workers)
+
+		require.Equal(t, Best{I: 8, J: 0, Magnitude: 3993}, best)
+	}
+}
+
+func TestLargestMagnitudeOfZeros(t *testing.T) {
+	numbers := []FlatNumber{parse(t, "[0,0]"), parse(t, "[0,0]")}
+
+	for _, workers := range []int{1, 3} {
+		require.Equal(t, Best{I: 0, J: 1, Magnitude: 0}, Default.LargestMagnitude(numbers, workers))
+	}
+	require.Equal(t, noBest, Default.LargestMagnitude(numbers[:1], 2))
+}
+
+func TestReduceInPlace(t *testing.T) {
+	n := parse(t, "[[[[[4,3],4],4],[7,[[8,4],9]]],[1,1]]")
+
+	require.Equal(t, "[[[[0,7],4],[[7,8],[6,0]]],[8,1]]", Default.reduceInPlace(n, nil).String())
+}
+
+func TestArithmeticDoesNotMutateInputs(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(n FlatNumber) FlatNumber
+	}{
+		{
+			name: "explode",
+			fn: func(n FlatNumber) FlatNumber {
+				res, _ := Explode(n)
+				return res
+			},
+		},
+		{
+			name: "split",
+			fn: func(n FlatNumber) FlatNumber {
+				res, _ := Split(n)
+				return res
+			},
+		},
+		{name: "reduce", fn: ReduceArray},
+		{
+			name: "add",
+			fn: func(n FlatNumber) FlatNumber {
+				return AddArray(n, n)
+			},
+		},
+	}
+
+	numbers := []string{
+		"[[[[[9,8],1],2],3],4]",
+		"[[6,[5,[4,[3,2]]]],1]",
+		"[[[[0,7],4],[15,[0,13]]],[1,1]]",
+		"[[[[0,7],4],[[7,8],[6,0]]],[8,1]]",
+	}
+
+	for _, test := range tests {
+		for _, number := range numbers {
+			t.Run(test.name+" "+number, func(t *testing.T) {
+				n := parse(t, number)
+				res := test.fn(n)
+				require.Equal(t, number, n.String())
+
+				for i := range res {
+					res[i] = 1
+				}
+				require.Equal(t, number, n.String())
+			})
+		}
+	}
+}
+
+func TestPart2ReusesNumbers(t *testing.T) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+	numbers, err := Default.parseNumbers(lines)
+	require.NoError(t, err)
+
+	before := make([]string, len(numbers))
+	for i, n := range numbers {
+		before[i] = n.String()
+	}
+
+	for i, n1 := range numbers {
+		for j, n2 := range numbers {
+			if i != j {
+				ReduceArray(AddArray(n1, n2))
+			}
+		}
+	}
+	Part1(numbers)
+	Part2(numbers)
+
+	for i, n := range numbers {
+		require.Equal(t, before[i], n.String())
+	}
+}
+
+func TestArithmeticVariants(t *testing.T) {
+	tests := []struct {
+		name       string
+		arithmetic Arithmetic
+		number     string
+		reduced    string
+		magnitude  int
+	}{
+		{
+			name:       "default",
+			arithmetic: Default,
+			number:     "[[[[[4,3],4],4],[7,[[8,4],9]]],[1,1]]",
+			reduced:    "[[[[0,7],4],[[7,8],[6,0]]],[8,1]]",
+			magnitude:  1384,
+		},
+		{
+			name:       "deeper explosions",
+			arithmetic: Arithmetic{ExplodeDepth: 6, SplitThreshold: 10, LeftWeight: 3, RightWeight: 2},
+			number:     "[[[[[4,3],4],4],[7,[[8,4],9]]],[1,1]]",
+			reduced:    "[[[[[4,3],4],4],[7,[[8,4],9]]],[1,1]]",
+			magnitude:  3250,
+		},
+		{
+			name:       "higher split threshold",
+			arithmetic: Arithmetic{ExplodeDepth: 5, SplitThreshold: 20, LeftWeight: 3, RightWeight: 2},
+			number:     "[[[[[4,3],4],4],[7,[[8,4],9]]],[1,1]]",
+			reduced:    "[[[[0,7],4],[15,[0,13]]],[1,1]]",
+			magnitude:  1042,
+		},
+		{
+			name:       "other weights",
+			arithmetic: Arithmetic{ExplodeDepth: 5, SplitThreshold: 10, LeftWeight: 1, RightWeight: 1},
+			number:     "[[[[[4,3],4],4],[7,[[8,4],9]]],[1,1]]",
+			reduced:    "[[[[0,7],4],[[7,8],[6,0]]],[8,1]]",
+			magnitude:  41,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reduced := test.arithmetic.Reduce(parse(t, test.number))
+
+			require.Equal(t, test.reduced, reduced.String())
+			require.Equal(t, test.magnitude, test.arithmetic.Magnitude(reduced))
+		})
+	}
+}
+
+func TestTreeNumberRoundTrip(t *testing.T) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+
+	for _, line := range append(lines, "[1,2]", "[[[[[9,8],1],2],3],4]") {
+		t.Run(line, func(t *testing.T) {
+			flat := parse(t, line)
+
+			tree, err := NewTreeNumber(flat)
+			require.NoError(t, err)
+			require.Equal(t, flat, tree.Flat())
+			require.Equal(t, line, tree.String())
+			require.Equal(t, flat.Magnitude(), tree.Magnitude(Default))
+			weights := Arithmetic{ExplodeDepth: 5, SplitThreshold: 10, LeftWeight: 5, RightWeight: 7}
+			require.Equal(t, weights.Magnitude(flat), tree.Magnitude(weights))
+		})
+	}
+}
+
+func TestNewTreeNumberErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		flat FlatNumber
+	}{
+		{name: "empty", flat: FlatNumber{}},
+		{name: "missing right element", flat: FlatNumber{Open, 1, Close}},
+		{name: "missing Close", flat: FlatNumber{Open, 1, 2}},
+		{name: "Close instead of an element", flat: FlatNumber{Close}},
+		{name: "trailing value", flat: FlatNumber{Open, 1, 2, Close, 3}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewTreeNumber(test.flat)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestTreeNumberNavigation(t *testing.T) {
+	n, err := ParseLine("[[1,[2,3]],4]")
+	require.NoError(t, err)
+
+	require.False(t, n.IsValue())
+	require.Nil(t, n.Parent())
+	require.Equal(t, 0, n.Depth())
+	require.Equal(t, 3, n.MaxDepth())
+
+	left := n.Left()
+	require.Equal(t, "[1,[2,3]]", left.String())
+	require.Same(t, n, left.Parent())
+	require.Equal(t, 1, left.Depth())
+	require.Equal(t, 2, left.MaxDepth())
+
+	four := n.Right()
+	require.True(t, four.IsValue())
+	require.Equal(t, 4, four.Value())
+	require.Equal(t, 0, four.MaxDepth())
+
+	three := left.Right().Right()
+	require.Equal(t, 3, three.Value())
+	require.Equal(t, 3, three.Depth())
+	require.Same(t, left, three.Parent().Parent())
+}
+
+func TestTreeNumberEqual(t *testing.T) {
+	tests := []struct {
+		n1    string
+		n2    string
+		equal bool
+	}{
+		{n1: "[1,2]", n2: "[1,2]", equal: true},
+		{n1: "[[1,2],3]", n2: "[[1,2],3]", equal: true},
+		{n1: "[1,2]", n2: "[2,1]", equal: false},
+		{n1: "[[1,2],3]", n2: "[1,[2,3]]", equal: false},
+		{n1: "[[1,2],3]", n2: "[[1,2],4]", equal: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.n1+" "+test.n2, func(t *testing.T) {
+			n1, err := ParseLine(test.n1)
+			require.NoError(t, err)
+			n2, err := ParseLine(test.n2)
+			require.NoError(t, err)
+
+			require.Equal(t, test.equal, n1.Equal(n2))
+			require.Equal(t, test.equal, n2.Equal(n1))
+		})
+	}
+}
+
+func TestArithmeticValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		arithmetic Arithmetic
+		err        string
+	}{
+		{name: "default", arithmetic: Default},
+		{name: "explode depth", arithmetic: Arithmetic{ExplodeDepth: 1, SplitThreshold: 10, LeftWeight: 3, RightWeight: 2}, err: "explode depth"},
+		{name: "split threshold", arithmetic: Arithmetic{ExplodeDepth: 5, SplitThreshold: 1, LeftWeight: 3, RightWeight: 2}, err: "split threshold"},
+		{name: "left weight", arithmetic: Arithmetic{ExplodeDepth: 5, SplitThreshold: 10, LeftWeight: 0, RightWeight: 2}, err: "weights"},
+		{name: "right weight", arithmetic: Arithmetic{ExplodeDepth: 5, SplitThreshold: 10, LeftWeight: 3, RightWeight: -1}, err: "weights"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.arithmetic.Validate()
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.err)
+		})
+	}
+}
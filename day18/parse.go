@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ParseError struct {
+	// Column of the error, starting at 1.
+	Column int
+	Reason string
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Reason)
+}
+
+// ParseErrors are the errors of several lines.
+type ParseErrors []error
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// ParseArray parses a reduced snailfish number.
+func ParseArray(line string) (FlatNumber, error) {
+	return Default.ParseArray(line)
+}
+
+// parseArray parses a snailfish number with at most maxDepth nested pairs, or
+// any number of them when maxDepth is 0.
+func parseArray(line string, maxDepth int) (FlatNumber, error) {
+	p := parser{line: line, maxDepth: maxDepth, res: make(FlatNumber, 0)}
+
+	if p.peek() != '[' {
+		return nil, p.errorf("a number must be a pair, got %s", p.current())
+	}
+	if err := p.element(0); err != nil {
+		return nil, err
+	}
+	if p.pos < len(line) {
+		if p.peek() == ']' {
+			return nil, p.errorf("unbalanced brackets: unexpected ']'")
+		}
+		return nil, p.errorf("unexpected %s after the number", p.current())
+	}
+
+	return p.res, nil
+}
+
+type parser struct {
+	line     string
+	pos      int
+	maxDepth int
+	res      FlatNumber
+}
+
+// peek returns the current character, or 0 at the end of the line.
+func (p *parser) peek() byte {
+	if p.pos >= len(p.line) {
+		return 0
+	}
+
+	return p.line[p.pos]
+}
+
+// current describes the current character for error messages.
+func (p *parser) current() string {
+	if p.pos >= len(p.line) {
+		return "end of line"
+	}
+
+	return fmt.Sprintf("%q", p.line[p.pos])
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return ParseError{Column: p.pos + 1, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) element(depth int) error {
+	c := p.peek()
+	if c >= '0' && c <= '9' {
+		p.value()
+		return nil
+	}
+	if c != '[' {
+		if c == 0 {
+			return p.errorf("unbalanced brackets: missing ']'")
+		}
+		return p.errorf("expected a value or a pair, got %s", p.current())
+	}
+
+	depth++
+	if p.maxDepth > 0 && depth > p.maxDepth {
+		return p.errorf("pair nested at depth %d, more than %d", depth, p.maxDepth)
+	}
+	p.res = append(p.res, Open)
+	p.pos++
+
+	if p.peek() == ']' {
+		return p.errorf("wrong arity: empty pair")
+	}
+	if err := p.element(depth); err != nil {
+		return err
+	}
+
+	switch p.peek() {
+	case ',':
+		p.pos++
+	case ']':
+		return p.errorf("wrong arity: pair with a single element")
+	case 0:
+		return p.errorf("unbalanced brackets: missing ']'")
+	default:
+		return p.errorf("missing comma, got %s", p.current())
+	}
+
+	if err := p.element(depth); err != nil {
+		return err
+	}
+
+	switch p.peek() {
+	case ']':
+		p.pos++
+	case ',':
+		return p.errorf("wrong arity: pair with more than two elements")
+	case 0:
+		return p.errorf("unbalanced brackets: missing ']'")
+	default:
+		return p.errorf("expected ']', got %s", p.current())
+	}
+	p.res = append(p.res, Close)
+
+	return nil
+}
+
+func (p *parser) value() {
+	v := 0
+	for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+		v = v*10 + int(c-'0')
+		p.pos++
+	}
+	p.res = append(p.res, v)
+}
@@ -0,0 +1,64 @@
+package main
+
+import "fmt"
+
+type Action int
+
+const (
+	ActionExplode Action = iota
+	ActionSplit
+)
+
+func (a Action) String() string {
+	if a == ActionExplode {
+		return "explode"
+	}
+
+	return "split"
+}
+
+// Reduction describes a single explode or split of a number.
+type Reduction struct {
+	Action Action
+	// Index of the exploded pair or the split value in the number before the
+	// step.
+	Index int
+
+	// Depth of the exploded pair.
+	Depth int
+	// Values of the exploded pair, carried to the regular values at LeftIndex
+	// and RightIndex, or lost when they are -1.
+	LeftValue  int
+	RightValue int
+	LeftIndex  int
+	RightIndex int
+
+	// Split value.
+	Value int
+
+	Result FlatNumber
+}
+
+// Details explains what the step did.
+func (r Reduction) Details() string {
+	if r.Action == ActionSplit {
+		return fmt.Sprintf("split %d at index %d", r.Value, r.Index)
+	}
+
+	return fmt.Sprintf("explode [%d,%d] at index %d, depth %d: left %s, right %s",
+		r.LeftValue, r.RightValue, r.Index, r.Depth, carry(r.LeftIndex), carry(r.RightIndex))
+}
+
+func carry(idx int) string {
+	if idx == -1 {
+		return "lost"
+	}
+
+	return fmt.Sprintf("to index %d", idx)
+}
+
+// String shows the number after the step, as in the puzzle walkthrough: the
+// numbers line up under the one of "after addition: ".
+func (r Reduction) String() string {
+	return fmt.Sprintf("after %-9s %s", r.Action.String()+":", r.Result)
+}
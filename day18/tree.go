@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+)
+
+// TreeNumber is a snailfish number as a binary tree: either a regular value,
+// or a pair with a left and a right element.
+type TreeNumber struct {
+	value  int
+	left   *TreeNumber
+	right  *TreeNumber
+	parent *TreeNumber
+}
+
+func NewValue(v int) *TreeNumber {
+	return &TreeNumber{value: v}
+}
+
+func NewPair(left, right *TreeNumber) *TreeNumber {
+	n := &TreeNumber{left: left, right: right}
+	left.parent = n
+	right.parent = n
+
+	return n
+}
+
+func ParseLine(line string) (*TreeNumber, error) {
+	flat, err := ParseArray(line)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTreeNumber(flat)
+}
+
+// NewTreeNumber builds the tree of a flat number, which must be a single
+// well-formed element.
+func NewTreeNumber(flat FlatNumber) (*TreeNumber, error) {
+	n, i, err := treeFrom(flat, 0)
+	if err != nil {
+		return nil, err
+	}
+	if i != len(flat) {
+		return nil, fmt.Errorf("unexpected %d after the number at index %d", flat[i], i)
+	}
+
+	return n, nil
+}
+
+// treeFrom builds the element starting at index i, and returns the index
+// following it.
+func treeFrom(flat FlatNumber, i int) (*TreeNumber, int, error) {
+	if i >= len(flat) {
+		return nil, i, fmt.Errorf("missing element at index %d", i)
+	}
+	if IsValue(flat[i]) {
+		return NewValue(flat[i]), i + 1, nil
+	}
+	if flat[i] != Open {
+		return nil, i, fmt.Errorf("expected an element at index %d, got %d", i, flat[i])
+	}
+
+	left, i, err := treeFrom(flat, i+1)
+	if err != nil {
+		return nil, i, err
+	}
+	right, i, err := treeFrom(flat, i)
+	if err != nil {
+		return nil, i, err
+	}
+	if i >= len(flat) || flat[i] != Close {
+		return nil, i, fmt.Errorf("missing Close at index %d", i)
+	}
+
+	return NewPair(left, right), i + 1, nil
+}
+
+func (n *TreeNumber) IsValue() bool {
+	return n.left == nil
+}
+
+func (n *TreeNumber) Value() int {
+	return n.value
+}
+
+func (n *TreeNumber) Left() *TreeNumber {
+	return n.left
+}
+
+func (n *TreeNumber) Right() *TreeNumber {
+	return n.right
+}
+
+func (n *TreeNumber) Parent() *TreeNumber {
+	return n.parent
+}
+
+// Depth is the number of pairs containing the element.
+func (n *TreeNumber) Depth() int {
+	depth := 0
+	for p := n.parent; p != nil; p = p.parent {
+		depth++
+	}
+
+	return depth
+}
+
+// MaxDepth is the highest number of nested pairs in the element, a regular
+// value having a max depth of 0.
+func (n *TreeNumber) MaxDepth() int {
+	if n.IsValue() {
+		return 0
+	}
+
+	left, right := n.left.MaxDepth(), n.right.MaxDepth()
+	if left > right {
+		return left + 1
+	}
+
+	return right + 1
+}
+
+// Equal tells whether both numbers have the same structure and values.
+func (n *TreeNumber) Equal(other *TreeNumber) bool {
+	if n.IsValue() || other.IsValue() {
+		return n.IsValue() && other.IsValue() && n.value == other.value
+	}
+
+	return n.left.Equal(other.left) && n.right.Equal(other.right)
+}
+
+func (n *TreeNumber) Magnitude(a Arithmetic) int {
+	if n.IsValue() {
+		return n.value
+	}
+
+	return a.LeftWeight*n.left.Magnitude(a) + a.RightWeight*n.right.Magnitude(a)
+}
+
+func (n *TreeNumber) Flat() FlatNumber {
+	return n.appendFlat(make(FlatNumber, 0))
+}
+
+func (n *TreeNumber) appendFlat(res FlatNumber) FlatNumber {
+	if n.IsValue() {
+		return append(res, n.value)
+	}
+
+	res = append(res, Open)
+	res = n.left.appendFlat(res)
+	res = n.right.appendFlat(res)
+
+	return append(res, Close)
+}
+
+func (n *TreeNumber) String() string {
+	if n.IsValue() {
+		return fmt.Sprintf("%d", n.value)
+	}
+
+	return fmt.Sprintf("[%s,%s]", n.left, n.right)
+}
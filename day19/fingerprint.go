@@ -0,0 +1,85 @@
+package main
+
+import "github.com/gverger/advent2021/utils"
+
+// Fingerprint indexes the squared distances between the beacons of a scanner.
+// They do not depend on the orientation of the scanner, so two scanners seeing
+// the same beacons share these distances.
+type Fingerprint struct {
+	// beaconDists counts the distances from each beacon to the beacons of the
+	// scanner, itself included.
+	beaconDists []map[int]int
+	// pairDists counts the distances between two different beacons.
+	pairDists map[int]int
+}
+
+func NewFingerprint(beacons []Beacon) Fingerprint {
+	fp := Fingerprint{
+		beaconDists: make([]map[int]int, len(beacons)),
+		pairDists:   make(map[int]int),
+	}
+
+	for i := range beacons {
+		fp.beaconDists[i] = map[int]int{0: 1}
+	}
+
+	for i, b := range beacons {
+		for j := i + 1; j < len(beacons); j++ {
+			d := b.sqDistTo(beacons[j])
+			fp.beaconDists[i][d]++
+			fp.beaconDists[j][d]++
+			fp.pairDists[d]++
+		}
+	}
+
+	return fp
+}
+
+// MayOverlap is a quick check telling whether two scanners can have
+// `overlap` beacons in common: they would then share the distances of all the
+// pairs of these beacons.
+func (fp Fingerprint) MayOverlap(other Fingerprint, overlap int) bool {
+	return sharedCount(fp.pairDists, other.pairDists) >= overlap*(overlap-1)/2
+}
+
+// Candidates returns the indices of the beacons of both scanners sharing at
+// least `overlap` distances with a beacon of the other scanner.
+func (fp Fingerprint) Candidates(other Fingerprint, overlap int) ([]int, []int) {
+	same1 := make([]int, 0)
+	same2 := make(map[int]bool)
+
+	for i, dists := range fp.beaconDists {
+		found := false
+		for j, dists2 := range other.beaconDists {
+			if sharedCount(dists, dists2) >= overlap {
+				found = true
+				same2[j] = true
+			}
+		}
+		if found {
+			same1 = append(same1, i)
+		}
+	}
+
+	res2 := make([]int, 0, len(same2))
+	for j := range other.beaconDists {
+		if same2[j] {
+			res2 = append(res2, j)
+		}
+	}
+
+	return same1, res2
+}
+
+func sharedCount(counts1, counts2 map[int]int) int {
+	if len(counts2) < len(counts1) {
+		counts1, counts2 = counts2, counts1
+	}
+
+	shared := 0
+	for d, nb := range counts1 {
+		shared += utils.Min(nb, counts2[d])
+	}
+
+	return shared
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+
+	"github.com/gverger/advent2021/utils"
+)
+
+type WorldConfig struct {
+	NbBeacons  int
+	NbScanners int
+	// Beacons are in [-Size, Size] on every axis.
+	Size int
+	// Range is the distance on every axis up to which a scanner sees beacons.
+	Range int
+	// Step is the largest distance on every axis between a scanner and the
+	// scanner it is placed next to, so that they can overlap. Scanners stay
+	// in [-(Size-Range), Size-Range] so they only see the world.
+	Step int
+}
+
+func DefaultWorldConfig(nbScanners int) WorldConfig {
+	return WorldConfig{NbBeacons: 400, NbScanners: nbScanners, Size: 2000, Range: 1000, Step: 300}
+}
+
+// World is a generated set of beacons and scanners, scanner 0 being at the
+// origin with no rotation so that its coordinates are the ones of the world.
+type World struct {
+	Beacons  []Beacon
+	Scanners []Scanner
+	// Transforms move the beacons of each scanner to the world coordinates.
+	Transforms []Transform
+}
+
+func GenerateWorld(rng *rand.Rand, cfg WorldConfig) World {
+	w := World{
+		Beacons:    make([]Beacon, 0, cfg.NbBeacons),
+		Scanners:   make([]Scanner, 0, cfg.NbScanners),
+		Transforms: make([]Transform, 0, cfg.NbScanners),
+	}
+
+	seen := make(map[Beacon]bool)
+	for len(w.Beacons) < cfg.NbBeacons {
+		b := randomBeacon(rng, cfg.Size)
+		if !seen[b] {
+			seen[b] = true
+			w.Beacons = append(w.Beacons, b)
+		}
+	}
+
+	for i := 0; i < cfg.NbScanners; i++ {
+		tr := Identity()
+		if i > 0 {
+			next := w.Transforms[rng.Intn(i)].Translation.add(randomBeacon(rng, cfg.Step))
+			next = Beacon{
+				x: clamp(next.x, cfg.Size-cfg.Range),
+				y: clamp(next.y, cfg.Size-cfg.Range),
+				z: clamp(next.z, cfg.Size-cfg.Range),
+			}
+			tr = Transform{Rotation: ProperRotations[rng.Intn(len(ProperRotations))], Translation: next}
+		}
+
+		s := newScanner(fmt.Sprintf("scanner %d", i))
+		toScanner := tr.Inverse()
+		for _, b := range w.Beacons {
+			local := toScanner.Apply(b)
+			if utils.Abs(local.x) <= cfg.Range && utils.Abs(local.y) <= cfg.Range && utils.Abs(local.z) <= cfg.Range {
+				s.beacons = append(s.beacons, local)
+			}
+		}
+		s.fingerprint = NewFingerprint(s.beacons)
+
+		w.Scanners = append(w.Scanners, *s)
+		w.Transforms = append(w.Transforms, tr)
+	}
+
+	return w
+}
+
+func randomBeacon(rng *rand.Rand, size int) Beacon {
+	coord := func() int { return rng.Intn(2*size+1) - size }
+
+	return Beacon{x: coord(), y: coord(), z: coord()}
+}
+
+func clamp(v int, limit int) int {
+	return utils.Max(-limit, utils.Min(v, limit))
+}
+
+// Lines returns the report of the scanners, as read by run.
+func (w World) Lines() []string {
+	res := make([]string, 0)
+	for i, s := range w.Scanners {
+		if i > 0 {
+			res = append(res, "")
+		}
+		res = append(res, fmt.Sprintf("--- %s ---", s.name))
+		for _, b := range s.beacons {
+			res = append(res, fmt.Sprintf("%d,%d,%d", b.x, b.y, b.z))
+		}
+	}
+
+	return res
+}
+
+// VisibleBeacons returns the sorted beacons seen by at least one scanner.
+func (w World) VisibleBeacons() []Beacon {
+	visible := make(map[Beacon]bool)
+	for i, s := range w.Scanners {
+		for _, b := range s.beacons {
+			visible[w.Transforms[i].Apply(b)] = true
+		}
+	}
+
+	res := make([]Beacon, 0, len(visible))
+	for b := range visible {
+		res = append(res, b)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].less(res[j]) })
+
+	return res
+}
+
+// Truth is the expected report when every scanner can be aligned.
+func (w World) Truth() Report {
+	scanners := make([]*Scanner, len(w.Scanners))
+	a := Alignment{
+		Transforms: w.Transforms,
+		Aligned:    make([]bool, len(w.Scanners)),
+		Parents:    make([]int, len(w.Scanners)),
+		Order:      make([]int, len(w.Scanners)),
+	}
+	for i := range w.Scanners {
+		scanners[i] = &w.Scanners[i]
+		a.Aligned[i] = true
+		a.Parents[i] = -1
+		a.Order[i] = i
+	}
+
+	return NewReport(scanners, a, w.VisibleBeacons())
+}
+
+func (w World) WriteTo(out io.Writer) (int64, error) {
+	var written int64
+	for _, l := range w.Lines() {
+		n, err := fmt.Fprintln(out, l)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
-	"math"
+	"math/rand"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -10,164 +14,165 @@ import (
 	"github.com/gverger/advent2021/utils/maps"
 )
 
+var (
+	workers    = flag.Int("workers", 0, "number of scanner pairs matched in parallel, the number of CPUs by default")
+	overlap    = flag.Int("overlap", 12, "number of common beacons for two scanners to overlap")
+	asJSON     = flag.Bool("json", false, "print the scanners and beacons as JSON")
+	components = flag.Bool("components", false, "print every group of overlapping scanners")
+
+	generate = flag.Int("generate", 0, "number of scanners of a random world to print instead of solving the input")
+	seed     = flag.Int64("seed", 1, "seed of the random world")
+	truth    = flag.String("truth", "truth.json", "file receiving the expected report of the random world")
+)
+
 func main() {
+	flag.Parse()
+
+	if *generate > 0 {
+		if err := generateWorld(); err != nil {
+			fmt.Println("ERROR:", err)
+			os.Exit(2)
+		}
+		return
+	}
+
 	utils.Main(run)
 }
 
-func run(lines []string) error {
-	scanners := make([]*Scanner, 0)
-	var currentScanner *Scanner
+func generateWorld() error {
+	w := GenerateWorld(rand.New(rand.NewSource(*seed)), DefaultWorldConfig(*generate))
 
-	for _, l := range lines {
-		if len(l) == 0 {
-			continue
-		}
-		if strings.HasPrefix(l, "---") {
-			currentScanner = newScanner(l[4 : len(l)-4])
-			scanners = append(scanners, currentScanner)
-			continue
-		}
+	if _, err := w.WriteTo(os.Stdout); err != nil {
+		return err
+	}
 
-		currentScanner.beacons = append(currentScanner.beacons, parseBeacon(l))
+	f, err := os.Create(*truth)
+	if err != nil {
+		return fmt.Errorf("cannot create %q: %w", *truth, err)
 	}
+	defer f.Close()
 
-	done := make([]bool, len(scanners))
-	todo := make([]int, 0)
-	trBeacons := make([]func(Beacon) Beacon, len(scanners))
-
-	trBeacons[0] = func(b Beacon) Beacon { return b }
-	todo = append(todo, 0)
-	done[0] = true
-
-	for len(todo) > 0 {
-		current := todo[0]
-		todo = todo[1:]
-		// done[current] = true
-		scanner := scanners[current]
-
-		for i, s := range scanners {
-			if done[i] {
-				continue
-			}
-
-			f, ok := rotationBetweenScanners(*scanner, *s)
-			if ok {
-				fmt.Printf("Close scanners: %s and %s\n", scanner.name, s.name)
-				todo = append(todo, i)
-				trBeacons[i] = func(b Beacon) Beacon { return trBeacons[current](f(b)) }
-				done[i] = true
-			}
-		}
+	return json.NewEncoder(f).Encode(w.Truth())
+}
+
+// MinOverlap is the lowest overlap that fixes the transform between two
+// scanners: with fewer common beacons, any rotation matches.
+const MinOverlap = 3
+
+func checkOverlap(overlap int) error {
+	if overlap < MinOverlap {
+		return fmt.Errorf("overlap %d is too low, it must be at least %d", overlap, MinOverlap)
 	}
 
-	allBeacons := make(map[Beacon]bool)
-	for i, s := range scanners {
-		tr := trBeacons[i]
-		for _, b := range s.beacons {
-			allBeacons[tr(b)] = true
-		}
+	return nil
+}
+
+func run(lines []string) error {
+	if err := checkOverlap(*overlap); err != nil {
+		return err
 	}
 
-	fmt.Println("Count all =", len(allBeacons))
+	scanners := parseScanners(lines)
 
-	scannerPos := make([]Beacon, len(scanners))
+	if *components {
+		for _, a := range alignComponents(scanners, *overlap, *workers) {
+			fmt.Printf("Component of %s: %d scanners, %d beacons, ocean size %d\n",
+				scanners[a.Root].name, len(a.Order), len(mergeBeacons(scanners, a)), oceanSize(a))
+		}
+		return nil
+	}
+
+	alignment := alignScanners(scanners, *overlap, *workers)
+	beacons := mergeBeacons(scanners, alignment)
 
-	for i := range scanners {
-		scannerPos[i] = trBeacons[i](Beacon{})
+	if *asJSON {
+		return printJSON(scanners, alignment, beacons)
 	}
 
-	oceanSize := 0
-	for i := range scannerPos {
-		for j := range scannerPos {
-			oceanSize = utils.Max(oceanSize, int(scannerPos[i].manDistTo(scannerPos[j])))
-		}
+	for _, i := range alignment.Order[1:] {
+		fmt.Printf("Close scanners: %s and %s\n", scanners[alignment.Parents[i]].name, scanners[i].name)
+	}
+	for _, i := range alignment.Unaligned() {
+		fmt.Printf("WARNING: %s cannot be aligned with %s\n", scanners[i].name, scanners[alignment.Root].name)
 	}
 
-	fmt.Println("Ocean Size =", oceanSize)
+	fmt.Println("Count all =", len(beacons))
+	fmt.Println("Ocean Size =", oceanSize(alignment))
 
 	return nil
 }
 
-func rotationBetweenScanners(s1, s2 Scanner) (func(Beacon) Beacon, bool) {
-	d1 := distMaps(s1)
-	d2 := distMaps(s2)
-
-	same1 := make([]Beacon, 0)
-	same2 := make([]Beacon, 0)
-	for b1, dists := range d1 {
-		for b2, dists2 := range d2 {
-			sameDists := 0
-			for d, nb := range dists {
-				sameDists += utils.Min(nb, dists2[d])
-			}
-			if sameDists >= 12 {
-				same1 = append(same1, b1)
-				same2 = append(same2, b2)
-				break
-			}
+// oceanSize is the largest manhattan distance between two aligned scanners.
+func oceanSize(alignment Alignment) int {
+	size := 0
+	for _, i := range alignment.Order {
+		for _, j := range alignment.Order {
+			pos1 := alignment.Transforms[i].Translation
+			pos2 := alignment.Transforms[j].Translation
+			size = utils.Max(size, pos1.manDistTo(pos2))
 		}
 	}
 
-	if len(same1) >= 12 {
-		same1, same2 = reallySame(same1, same2)
-		if len(same1) >= 12 {
-			return rotationBetweenBeacons(same1, same2)
-		}
-	}
-	return nil, false
+	return size
 }
 
-func rotationBetweenBeacons(same1, same2 []Beacon) (func(Beacon) Beacon, bool) {
-	if len(same1) == 0 {
-		return func(b Beacon) Beacon { return b }, false
-	}
+func parseScanners(lines []string) []*Scanner {
+	scanners := make([]*Scanner, 0)
+	var currentScanner *Scanner
 
-	b1s := make([]Beacon, len(same1))
-	o1 := same1[0]
-	for i, b := range same1 {
-		b1s[i] = Beacon{x: b.x - o1.x, y: b.y - o1.y, z: b.z - o1.z}
-	}
+	for _, l := range lines {
+		if len(l) == 0 {
+			continue
+		}
+		if strings.HasPrefix(l, "---") {
+			currentScanner = newScanner(l[4 : len(l)-4])
+			scanners = append(scanners, currentScanner)
+			continue
+		}
 
-	b2s := make([]Beacon, len(same2))
-	o2 := same2[0]
-	for i, b := range same2 {
-		b2s[i] = Beacon{x: b.x - o2.x, y: b.y - o2.y, z: b.z - o2.z}
+		currentScanner.beacons = append(currentScanner.beacons, parseBeacon(l))
 	}
 
-	idx := 1
-	for b2s[idx].x == b2s[idx].y || b2s[idx].y == b2s[idx].z || b2s[idx].x == b2s[idx].z {
-		idx += 1
+	for _, s := range scanners {
+		s.fingerprint = NewFingerprint(s.beacons)
 	}
-	rx := findRotation(b1s[idx].x, b2s[idx])
-	ry := findRotation(b1s[idx].y, b2s[idx])
-	rz := findRotation(b1s[idx].z, b2s[idx])
 
-	for i := range b2s {
-		b := b2s[i]
-		b2s[i] = Beacon{x: rx(b), y: ry(b), z: rz(b)}
-	}
+	return scanners
+}
 
-	nbOk := 0
-	for i := range b2s {
-		b1 := b1s[i]
-		b2 := b2s[i]
-		if b1 == b2 {
-			nbOk++
+// mergeBeacons returns the sorted beacons of the aligned scanners, in the
+// coordinates of the root scanner.
+func mergeBeacons(scanners []*Scanner, alignment Alignment) []Beacon {
+	allBeacons := make(map[Beacon]bool)
+	for _, i := range alignment.Order {
+		tr := alignment.Transforms[i]
+		for _, b := range scanners[i].beacons {
+			allBeacons[tr.Apply(b)] = true
 		}
 	}
 
-	if nbOk < 12 {
-		return nil, false
+	res := make([]Beacon, 0, len(allBeacons))
+	for b := range allBeacons {
+		res = append(res, b)
 	}
+	sort.Slice(res, func(i, j int) bool { return res[i].less(res[j]) })
 
-	f := func(b Beacon) Beacon {
-		b = Beacon{x: b.x - o2.x, y: b.y - o2.y, z: b.z - o2.z}
-		b = Beacon{x: rx(b), y: ry(b), z: rz(b)}
-		b = Beacon{x: b.x + o1.x, y: b.y + o1.y, z: b.z + o1.z}
-		return b
+	return res
+}
+
+// rotationBetweenScanners returns the transform from the coordinates of s2 to
+// the ones of s1, when they have at least `overlap` beacons in common.
+func rotationBetweenScanners(s1, s2 Scanner, overlap int) (Transform, bool) {
+	if !s1.fingerprint.MayOverlap(s2.fingerprint, overlap) {
+		return Transform{}, false
+	}
+
+	same1, same2 := s1.fingerprint.Candidates(s2.fingerprint, overlap)
+	if len(same1) < overlap || len(same2) < overlap {
+		return Transform{}, false
 	}
 
-	return f, true
+	return align(s1.beaconsAt(same1), s2.beaconsAt(same2), overlap)
 }
 
 type Beacon struct {
@@ -184,134 +189,58 @@ func parseBeacon(line string) Beacon {
 	return Beacon{x: coords[0], y: coords[1], z: coords[2]}
 }
 
-func (b Beacon) distTo(other Beacon) float64 {
-	return math.Sqrt(math.Pow(float64(b.x-other.x), 2) + math.Pow(float64(b.y-other.y), 2) + math.Pow(float64(b.z-other.z), 2))
-}
-
-func (b Beacon) manDistTo(other Beacon) int {
-	return utils.Abs(b.x-other.x) + utils.Abs(b.y-other.y) + utils.Abs(b.z-other.z)
-}
+func (b Beacon) sqDistTo(other Beacon) int {
+	d := b.sub(other)
 
-type Scanner struct {
-	name    string
-	beacons []Beacon
+	return d.x*d.x + d.y*d.y + d.z*d.z
 }
 
-func newScanner(name string) *Scanner {
-	return &Scanner{name: name, beacons: make([]Beacon, 0)}
+func (b Beacon) add(other Beacon) Beacon {
+	return Beacon{x: b.x + other.x, y: b.y + other.y, z: b.z + other.z}
 }
 
-func toInt(number string) int {
-	n, _ := strconv.Atoi(number)
-	return n
+func (b Beacon) sub(other Beacon) Beacon {
+	return Beacon{x: b.x - other.x, y: b.y - other.y, z: b.z - other.z}
 }
 
-func (s Scanner) Distances() map[Beacon][]float64 {
-	res := make(map[Beacon][]float64)
-	for _, b := range s.beacons {
-		res[b] = make([]float64, 0, len(s.beacons))
-		for _, b2 := range s.beacons {
-			res[b] = append(res[b], b.distTo(b2))
-		}
-	}
-
-	return res
+func (b Beacon) Coords() [3]int {
+	return [3]int{b.x, b.y, b.z}
 }
 
-func reallySame(same1, same2 []Beacon) ([]Beacon, []Beacon) {
-	if len(same1) == 0 {
-		return nil, nil
+func (b Beacon) less(other Beacon) bool {
+	if b.x != other.x {
+		return b.x < other.x
 	}
-
-	b1s := make([]Beacon, len(same1))
-	o1 := same1[0]
-	for i, b := range same1 {
-		b1s[i] = Beacon{x: b.x - o1.x, y: b.y - o1.y, z: b.z - o1.z}
+	if b.y != other.y {
+		return b.y < other.y
 	}
 
-	b2s := make([]Beacon, len(same2))
-	o2 := same2[0]
-	for i, b := range same2 {
-		b2s[i] = Beacon{x: b.x - o2.x, y: b.y - o2.y, z: b.z - o2.z}
-	}
-
-	idx := 1
-	for b2s[idx].x == b2s[idx].y || b2s[idx].y == b2s[idx].z || b2s[idx].x == b2s[idx].z {
-		idx += 1
-	}
-	rx := findRotation(b1s[idx].x, b2s[idx])
-	ry := findRotation(b1s[idx].y, b2s[idx])
-	rz := findRotation(b1s[idx].z, b2s[idx])
-
-	for i := range b2s {
-		b := b2s[i]
-		b2s[i] = Beacon{x: rx(b), y: ry(b), z: rz(b)}
-	}
-
-	res1 := make([]Beacon, 0)
-	res2 := make([]Beacon, 0)
-
-	for i := range b2s {
-		b1 := b1s[i]
-		b2 := b2s[i]
-		if b1 == b2 {
-			res1 = append(res1, same1[i])
-			res2 = append(res2, same2[i])
-		} else {
-			fmt.Println("ERROR")
-		}
-	}
-
-	return res1, res2
+	return b.z < other.z
 }
 
-func findRotation(goal int, b Beacon) func(Beacon) int {
-	for _, r := range Rotations {
-		if r(b) == goal {
-			return r
-		}
-	}
+func (b Beacon) manDistTo(other Beacon) int {
+	return utils.Abs(b.x-other.x) + utils.Abs(b.y-other.y) + utils.Abs(b.z-other.z)
+}
 
-	return nil
+type Scanner struct {
+	name        string
+	beacons     []Beacon
+	fingerprint Fingerprint
 }
 
-func X(b Beacon) int      { return b.x }
-func MinusX(b Beacon) int { return -b.x }
-func Y(b Beacon) int      { return b.y }
-func MinusY(b Beacon) int { return -b.y }
-func Z(b Beacon) int      { return b.z }
-func MinusZ(b Beacon) int { return -b.z }
-
-var Rotations = []func(Beacon) int{X, MinusX, Y, MinusY, Z, MinusZ}
-
-func same12Dists(s1, s2 Scanner) bool {
-	d1 := distMaps(s1)
-	d2 := distMaps(s2)
-
-	nbSame := 0
-	for _, dists := range d1 {
-		for _, dists2 := range d2 {
-			sameDists := 0
-			for d, nb := range dists {
-				sameDists += utils.Min(nb, dists2[d])
-			}
-			if sameDists >= 12 {
-				nbSame++
-				break
-			}
-		}
-	}
+func newScanner(name string) *Scanner {
+	return &Scanner{name: name, beacons: make([]Beacon, 0)}
+}
 
-	return nbSame >= 12
+func toInt(number string) int {
+	n, _ := strconv.Atoi(number)
+	return n
 }
 
-func distMaps(s Scanner) map[Beacon]map[float64]int {
-	res := make(map[Beacon]map[float64]int)
-	for b, dists := range s.Distances() {
-		res[b] = make(map[float64]int)
-		for _, d := range dists {
-			res[b][d] += 1
-		}
+func (s Scanner) beaconsAt(indices []int) []Beacon {
+	res := make([]Beacon, len(indices))
+	for i, idx := range indices {
+		res[i] = s.beacons[idx]
 	}
 
 	return res
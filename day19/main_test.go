@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gverger/advent2021/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProperRotations(t *testing.T) {
+	seen := make(map[Matrix]bool)
+	for _, r := range ProperRotations {
+		require.Equal(t, 1, r.Det())
+		require.Equal(t, IdentityMatrix(), r.Mul(r.Transpose()))
+		seen[r] = true
+	}
+
+	require.Len(t, seen, 24)
+}
+
+func TestPuzzleExample(t *testing.T) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+	scanners := parseScanners(lines)
+
+	for _, workers := range []int{1, 4} {
+		alignment := alignScanners(scanners, 12, workers)
+
+		require.Empty(t, alignment.Unaligned())
+		require.Len(t, mergeBeacons(scanners, alignment), 79)
+		require.Equal(t, 3621, oceanSize(alignment))
+		require.Equal(t, Beacon{x: 68, y: -1246, z: -43}, alignment.Transforms[1].Translation)
+	}
+}
+
+func TestGeneratedWorlds(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		w := GenerateWorld(rand.New(rand.NewSource(seed)), DefaultWorldConfig(10))
+		scanners := parseScanners(w.Lines())
+
+		alignment := alignScanners(scanners, 12, 0)
+		report := NewReport(scanners, alignment, mergeBeacons(scanners, alignment))
+
+		expected := w.Truth()
+		require.Empty(t, report.Unaligned)
+		require.Equal(t, expected.Beacons, report.Beacons)
+		require.ElementsMatch(t, expected.Scanners, withoutParents(report.Scanners))
+	}
+}
+
+func TestUnalignedScanners(t *testing.T) {
+	cfg := DefaultWorldConfig(3)
+	w := GenerateWorld(rand.New(rand.NewSource(1)), cfg)
+	expected := w.VisibleBeacons()
+	lonely := GenerateWorld(rand.New(rand.NewSource(2)), cfg)
+	lonely.Scanners[0].name = "scanner 3"
+	lonely.Scanners[1].name = "scanner 4"
+	w.Scanners = append(w.Scanners, lonely.Scanners[0], lonely.Scanners[1])
+
+	scanners := parseScanners(w.Lines())
+
+	alignment := alignScanners(scanners, 12, 0)
+	require.Equal(t, []int{3, 4}, alignment.Unaligned())
+	require.Equal(t, expected, mergeBeacons(scanners, alignment))
+
+	components := alignComponents(scanners, 12, 0)
+	require.Len(t, components, 2)
+	require.Equal(t, 0, components[0].Root)
+	require.Equal(t, []int{0, 1, 2}, components[0].Order)
+	require.Equal(t, 3, components[1].Root)
+	require.Equal(t, []int{3, 4}, components[1].Order)
+
+	lonely.Scanners = lonely.Scanners[:2]
+	lonely.Transforms = lonely.Transforms[:2]
+	require.Equal(t, lonely.VisibleBeacons(), mergeBeacons(scanners, components[1]))
+}
+
+func withoutParents(reports []ScannerReport) []ScannerReport {
+	res := make([]ScannerReport, len(reports))
+	for i, r := range reports {
+		r.Parent = ""
+		res[i] = r
+	}
+
+	return res
+}
+
+func TestCheckOverlap(t *testing.T) {
+	require.Error(t, checkOverlap(0))
+	require.Error(t, checkOverlap(2))
+	require.NoError(t, checkOverlap(3))
+	require.NoError(t, checkOverlap(12))
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Match is an overlap between two scanners, the transform moving the beacons
+// of To to the coordinates of From.
+type Match struct {
+	From      int
+	To        int
+	Transform Transform
+}
+
+// Alignment places the scanners in the coordinates of the root scanner. Only
+// the scanners connected to the root through overlaps are aligned.
+type Alignment struct {
+	Root       int
+	Transforms []Transform
+	Aligned    []bool
+	// Parents are the scanners each scanner was aligned against, -1 for the
+	// root and the unaligned scanners.
+	Parents []int
+	// Order lists the aligned scanners in the order they were found.
+	Order []int
+}
+
+// matchScanners tries every pair of scanners on a pool of workers,
+// runtime.NumCPU() of them when workers is not positive. The matches of each
+// scanner are sorted by the other scanner.
+func matchScanners(scanners []*Scanner, overlap int, workers int) [][]Match {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	type pair struct{ i, j int }
+	pairs := make(chan pair)
+	found := make(chan Match)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range pairs {
+				if tr, ok := rotationBetweenScanners(*scanners[p.i], *scanners[p.j], overlap); ok {
+					found <- Match{From: p.i, To: p.j, Transform: tr}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for i := range scanners {
+			for j := i + 1; j < len(scanners); j++ {
+				pairs <- pair{i: i, j: j}
+			}
+		}
+		close(pairs)
+		wg.Wait()
+		close(found)
+	}()
+
+	// matches[i][j] is the match from i to j, nil if they do not overlap
+	matches := make([][]*Match, len(scanners))
+	for i := range matches {
+		matches[i] = make([]*Match, len(scanners))
+	}
+	for m := range found {
+		m := m
+		matches[m.From][m.To] = &m
+		matches[m.To][m.From] = &Match{From: m.To, To: m.From, Transform: m.Transform.Inverse()}
+	}
+
+	res := make([][]Match, len(scanners))
+	for i := range matches {
+		res[i] = make([]Match, 0)
+		for _, m := range matches[i] {
+			if m != nil {
+				res[i] = append(res[i], *m)
+			}
+		}
+	}
+
+	return res
+}
+
+// alignScanners aligns the scanners connected to scanner 0.
+func alignScanners(scanners []*Scanner, overlap int, workers int) Alignment {
+	return alignFrom(matchScanners(scanners, overlap, workers), 0)
+}
+
+// alignComponents splits the scanners into groups that overlap with each
+// other, each group being aligned on its first scanner.
+func alignComponents(scanners []*Scanner, overlap int, workers int) []Alignment {
+	matches := matchScanners(scanners, overlap, workers)
+
+	res := make([]Alignment, 0)
+	aligned := make([]bool, len(scanners))
+	for root := range scanners {
+		if aligned[root] {
+			continue
+		}
+
+		a := alignFrom(matches, root)
+		for _, i := range a.Order {
+			aligned[i] = true
+		}
+		res = append(res, a)
+	}
+
+	return res
+}
+
+// alignFrom aligns the scanners with a breadth first search from root, going
+// through the matches in order so the result does not depend on the workers.
+func alignFrom(matches [][]Match, root int) Alignment {
+	a := Alignment{
+		Root:       root,
+		Transforms: make([]Transform, len(matches)),
+		Aligned:    make([]bool, len(matches)),
+		Parents:    make([]int, len(matches)),
+		Order:      []int{root},
+	}
+	for i := range a.Parents {
+		a.Parents[i] = -1
+	}
+
+	a.Transforms[root] = Identity()
+	a.Aligned[root] = true
+
+	for k := 0; k < len(a.Order); k++ {
+		current := a.Order[k]
+
+		for _, m := range matches[current] {
+			if a.Aligned[m.To] {
+				continue
+			}
+
+			a.Transforms[m.To] = m.Transform.Then(a.Transforms[current])
+			a.Aligned[m.To] = true
+			a.Parents[m.To] = current
+			a.Order = append(a.Order, m.To)
+		}
+	}
+
+	return a
+}
+
+// Unaligned returns the scanners that could not be aligned on the root.
+func (a Alignment) Unaligned() []int {
+	res := make([]int, 0)
+	for i, ok := range a.Aligned {
+		if !ok {
+			res = append(res, i)
+		}
+	}
+
+	return res
+}
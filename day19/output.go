@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+type ScannerReport struct {
+	Name     string `json:"name"`
+	Position [3]int `json:"position"`
+	// Rotation moves the beacons seen by the scanner to the orientation of
+	// scanner 0.
+	Rotation Matrix `json:"rotation"`
+	// Parent is the scanner it was aligned against, empty for scanner 0.
+	Parent string `json:"parent,omitempty"`
+}
+
+type Report struct {
+	Scanners  []ScannerReport `json:"scanners"`
+	Unaligned []string        `json:"unaligned"`
+	Beacons   [][3]int        `json:"beacons"`
+}
+
+func NewReport(scanners []*Scanner, alignment Alignment, beacons []Beacon) Report {
+	r := Report{
+		Scanners:  make([]ScannerReport, 0, len(scanners)),
+		Unaligned: make([]string, 0),
+		Beacons:   make([][3]int, len(beacons)),
+	}
+
+	for _, i := range alignment.Order {
+		tr := alignment.Transforms[i]
+		s := ScannerReport{Name: scanners[i].name, Position: tr.Translation.Coords(), Rotation: tr.Rotation}
+		if p := alignment.Parents[i]; p != -1 {
+			s.Parent = scanners[p].name
+		}
+		r.Scanners = append(r.Scanners, s)
+	}
+
+	for _, i := range alignment.Unaligned() {
+		r.Unaligned = append(r.Unaligned, scanners[i].name)
+	}
+
+	for i, b := range beacons {
+		r.Beacons[i] = b.Coords()
+	}
+
+	return r
+}
+
+func printJSON(scanners []*Scanner, alignment Alignment, beacons []Beacon) error {
+	return json.NewEncoder(os.Stdout).Encode(NewReport(scanners, alignment, beacons))
+}
@@ -0,0 +1,129 @@
+package main
+
+// Matrix is a 3x3 integer matrix, applied to column vectors.
+type Matrix [3][3]int
+
+// ProperRotations are the 24 rotations keeping the axes on the axes. They have
+// a determinant of 1, so none of them is a reflection.
+var ProperRotations = properRotations()
+
+func properRotations() []Matrix {
+	res := make([]Matrix, 0, 24)
+
+	permutations := [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+	for _, p := range permutations {
+		for signs := 0; signs < 8; signs++ {
+			var m Matrix
+			for row := 0; row < 3; row++ {
+				m[row][p[row]] = 1
+				if signs&(1<<row) != 0 {
+					m[row][p[row]] = -1
+				}
+			}
+			if m.Det() == 1 {
+				res = append(res, m)
+			}
+		}
+	}
+
+	return res
+}
+
+func IdentityMatrix() Matrix {
+	return Matrix{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+}
+
+func (m Matrix) Det() int {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+func (m Matrix) Apply(b Beacon) Beacon {
+	return Beacon{
+		x: m[0][0]*b.x + m[0][1]*b.y + m[0][2]*b.z,
+		y: m[1][0]*b.x + m[1][1]*b.y + m[1][2]*b.z,
+		z: m[2][0]*b.x + m[2][1]*b.y + m[2][2]*b.z,
+	}
+}
+
+// Mul returns the matrix applying other, then m.
+func (m Matrix) Mul(other Matrix) Matrix {
+	var res Matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				res[i][j] += m[i][k] * other[k][j]
+			}
+		}
+	}
+
+	return res
+}
+
+// Transform moves beacons from the coordinates of a scanner to the
+// coordinates of another one: rotation first, then translation.
+type Transform struct {
+	Rotation    Matrix
+	Translation Beacon
+}
+
+func Identity() Transform {
+	return Transform{Rotation: IdentityMatrix()}
+}
+
+func (t Transform) Apply(b Beacon) Beacon {
+	return t.Rotation.Apply(b).add(t.Translation)
+}
+
+// Then returns the transform applying t, then next.
+func (t Transform) Then(next Transform) Transform {
+	return Transform{
+		Rotation:    next.Rotation.Mul(t.Rotation),
+		Translation: next.Apply(t.Translation),
+	}
+}
+
+// align finds the transform moving b2s to the coordinates of b1s, so that at
+// least `overlap` of them match beacons of b1s. Every proper rotation is tried, and
+// the translations vote for the best one.
+func align(b1s, b2s []Beacon, overlap int) (Transform, bool) {
+	for _, r := range ProperRotations {
+		votes := make(map[Beacon]int)
+		best := Beacon{}
+		for _, b2 := range b2s {
+			rotated := r.Apply(b2)
+			for _, b1 := range b1s {
+				offset := b1.sub(rotated)
+				votes[offset]++
+				if votes[offset] > votes[best] {
+					best = offset
+				}
+			}
+		}
+
+		if votes[best] >= overlap {
+			return Transform{Rotation: r, Translation: best}, true
+		}
+	}
+
+	return Transform{}, false
+}
+
+// Transpose is also the inverse of a rotation.
+func (m Matrix) Transpose() Matrix {
+	var res Matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			res[i][j] = m[j][i]
+		}
+	}
+
+	return res
+}
+
+func (t Transform) Inverse() Transform {
+	r := t.Rotation.Transpose()
+
+	return Transform{Rotation: r, Translation: Beacon{}.sub(r.Apply(t.Translation))}
+}
@@ -0,0 +1,48 @@
+package main
+
+import "github.com/gverger/advent2021/utils/automaton"
+
+// Synchronous is the rule as a cellular automaton over the automaton.Square
+// neighbourhood, which lists the pixels in the order of their bits.
+func (e EnhanceRule) Synchronous() automaton.Synchronous {
+	return func(_ automaton.State, neighbours []automaton.State) automaton.State {
+		index := 0
+		for _, s := range neighbours {
+			index = index*2 + int(s)
+		}
+
+		return automaton.State(e.For(index))
+	}
+}
+
+// Automaton converts the grid to an infinite automaton grid.
+func (g Grid) Automaton() *automaton.Grid {
+	a := automaton.NewGrid(g.width, g.height, automaton.Infinite)
+	a.Min = automaton.Point{X: g.min.x, Y: g.min.y}
+	a.Outside = automaton.State(g.outside)
+
+	for _, p := range a.Points() {
+		a.Set(p, automaton.State(g.At(Point{x: p.X, y: p.Y})))
+	}
+
+	return a
+}
+
+func NewGridFromAutomaton(a *automaton.Grid) Grid {
+	g := NewGrid(Point{x: a.Min.X, y: a.Min.Y}, a.Width, a.Height, color(a.Outside))
+	for _, p := range a.Points() {
+		if a.At(p) == automaton.State(Light) {
+			g.Set(Point{x: p.X, y: p.Y})
+		}
+	}
+
+	return g
+}
+
+// EnhanceWithAutomaton is Enhance running on the generic automaton engine.
+func (g Grid) EnhanceWithAutomaton(r EnhanceRule) Grid {
+	a := g.Automaton()
+	r.Synchronous().Apply(a, automaton.Square)
+
+	return NewGridFromAutomaton(a)
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"math/bits"
 	"strings"
 
 	"github.com/gverger/advent2021/utils"
@@ -11,28 +14,73 @@ func main() {
 	utils.Main(run)
 }
 
+var (
+	steps   = flag.Int("steps", 50, "number of enhancements")
+	pngDir  = flag.String("png", "", "directory receiving a PNG image per step")
+	gifFile = flag.String("gif", "", "animated GIF file receiving all the steps")
+	scale   = flag.Int("scale", 4, "size in the images of a pixel")
+	window  = flag.String("window", "", "part of the image drawn, as x0,y0,x1,y1")
+	generic = flag.Bool("automaton", false, "enhance with the generic cellular automaton engine")
+)
+
 func run(lines []string) error {
-	r := NewEnhanceRule(lines[0])
+	if *steps < 0 {
+		return fmt.Errorf("steps %d cannot be negative", *steps)
+	}
+	if len(lines) < 3 {
+		return errors.New("expected a rule, an empty line and an image")
+	}
 
-	g := Grid{outside: Dark}
-	for j, line := range lines[2:] {
-		for i, c := range line {
-			if c == '#' {
-				g.Set(Point{x: i, y: j})
-			}
-		}
+	r, err := NewEnhanceRule(lines[0])
+	if err != nil {
+		return err
+	}
+	g, err := NewGridFromInput(lines[2:])
+	if err != nil {
+		return err
+	}
+
+	if b := r.Background(); b != BackgroundDark {
+		fmt.Println("WARNING: the background is", b)
+	}
 
+	w, err := parseWindow(*window)
+	if err != nil {
+		return err
 	}
+	if *scale < 1 {
+		return fmt.Errorf("scale %d must be at least 1", *scale)
+	}
+	renderer := Renderer{Scale: *scale, Window: w, PNGDir: *pngDir, GIFFile: *gifFile}
 
 	fmt.Println(g)
 
-	for i := 0; i < 50; i++ {
-		g = g.Enhance(r)
+	for i := 0; i < *steps; i++ {
+		if renderer.Enabled() {
+			renderer.Add(g)
+		}
+		if *generic {
+			g = g.EnhanceWithAutomaton(r)
+		} else {
+			g = g.Enhance(r)
+		}
+	}
+	if renderer.Enabled() {
+		renderer.Add(g)
 	}
 
 	fmt.Println(g)
 
-	fmt.Println(len(g.data))
+	if err := renderer.Write(); err != nil {
+		return err
+	}
+
+	nb, err := g.Count()
+	if err != nil {
+		return err
+	}
+	fmt.Println(nb)
+
 	return nil
 }
 
@@ -48,44 +96,68 @@ type Point struct {
 	y int
 }
 
+// Grid is an image stored as a bitset, row by row, each row starting on a new
+// word. Pixels outside of it all have the same color.
 type Grid struct {
-	data    map[Point]color
 	min     Point
-	max     Point
+	width   int
+	height  int
+	stride  int
+	bits    []uint64
 	outside color
 }
 
-func (g *Grid) Set(p Point) {
-	if g.data == nil {
-		g.data = make(map[Point]color)
-		g.min = p
-		g.max = p
-		g.data[p] = Light
-		return
-	}
+func NewGrid(min Point, width, height int, outside color) Grid {
+	stride := (width + 63) / 64
 
-	g.data[p] = Light
-	if p.x < g.min.x {
-		g.min.x = p.x
-	}
-	if p.y < g.min.y {
-		g.min.y = p.y
+	return Grid{
+		min:     min,
+		width:   width,
+		height:  height,
+		stride:  stride,
+		bits:    make([]uint64, stride*height),
+		outside: outside,
 	}
-	if p.x > g.max.x {
-		g.max.x = p.x
-	}
-	if p.y > g.max.y {
-		g.max.y = p.y
+}
+
+func NewGridFromInput(lines []string) (Grid, error) {
+	g := NewGrid(Point{}, len(lines[0]), len(lines), Dark)
+	for j, line := range lines {
+		if len(line) != g.width {
+			return Grid{}, fmt.Errorf("image line %d: %d pixels instead of %d", j+1, len(line), g.width)
+		}
+		for i, c := range line {
+			switch c {
+			case '#':
+				g.Set(Point{x: i, y: j})
+			case '.':
+			default:
+				return Grid{}, fmt.Errorf("image line %d: unexpected %q", j+1, c)
+			}
+		}
 	}
+
+	return g, nil
+}
+
+// Set lights a pixel of the grid, which must be inside of it.
+func (g Grid) Set(p Point) {
+	x, y := p.x-g.min.x, p.y-g.min.y
+	g.bits[y*g.stride+x/64] |= 1 << (x % 64)
+}
+
+func (g Grid) max() Point {
+	return Point{x: g.min.x + g.width - 1, y: g.min.y + g.height - 1}
 }
 
 func (g Grid) String() string {
 	var b strings.Builder
 
-	b.WriteString(fmt.Sprintf("GRID [%d,%d] --> [%d,%d]\n", g.min.x, g.min.y, g.max.x, g.max.y))
+	max := g.max()
+	b.WriteString(fmt.Sprintf("GRID [%d,%d] --> [%d,%d]\n", g.min.x, g.min.y, max.x, max.y))
 
-	for j := g.min.y; j <= g.max.y; j++ {
-		for i := g.min.x; i <= g.max.x; i++ {
+	for j := g.min.y; j <= max.y; j++ {
+		for i := g.min.x; i <= max.x; i++ {
 			c := "."
 			if g.At(Point{x: i, y: j}) == Light {
 				c = "#"
@@ -98,48 +170,68 @@ func (g Grid) String() string {
 }
 
 func (g Grid) At(p Point) color {
-	if p.x < g.min.x || p.y < g.min.y || p.x > g.max.x || p.y > g.max.y {
-		return g.outside
-	}
+	return color(g.bit(p.x-g.min.x, p.y-g.min.y))
+}
 
-	if c, ok := g.data[p]; ok {
-		return c
+// bit returns the pixel at (x, y) relative to the top left corner.
+func (g Grid) bit(x, y int) int {
+	if x < 0 || y < 0 || x >= g.width || y >= g.height {
+		return int(g.outside)
 	}
-	return Dark
+
+	return int(g.bits[y*g.stride+x/64]>>(x%64)) & 1
 }
 
-func (g Grid) Around(p Point) int {
-	res := 0
+// Count returns the number of light pixels, which is infinite when the
+// outside is light.
+func (g Grid) Count() (int, error) {
+	if g.outside == Light {
+		return 0, errors.New("infinite number of light pixels")
+	}
 
-	for j := p.y - 1; j <= p.y+1; j++ {
-		for i := p.x - 1; i <= p.x+1; i++ {
-			value := int(g.At(Point{x: i, y: j}))
-			res = res*2 + value
-		}
+	nb := 0
+	for _, w := range g.bits {
+		nb += bits.OnesCount64(w)
 	}
 
-	return res
+	return nb, nil
 }
 
+// Enhance returns a grid one pixel larger on every side. The 9 bits index of
+// each pixel is computed from the index of its left neighbour: every row of
+// the 3x3 window shifts left, and the new column comes in.
 func (g Grid) Enhance(r EnhanceRule) Grid {
-	eg := Grid{outside: r.For(g.Around(Point{x: g.min.x - 5, y: g.min.y - 5}))}
-
-	for j := g.min.y - 3; j <= g.max.y+3; j++ {
-		for i := g.min.x - 3; i <= g.max.x+3; i++ {
-			p := Point{x: i, y: j}
-			value := g.Around(p)
-			if r.For(value) == Light {
-				eg.Set(p)
+	eg := NewGrid(Point{x: g.min.x - 1, y: g.min.y - 1}, g.width+2, g.height+2, r.NextOutside(g.outside))
+
+	// pixel (x, y) of eg is pixel (x-1, y-1) of g
+	for y := 0; y < eg.height; y++ {
+		index := 0
+		for x := -2; x < eg.width; x++ {
+			index = (index<<1)&0b110_110_110 | g.bit(x, y-2)<<6 | g.bit(x, y-1)<<3 | g.bit(x, y)
+			if x >= 0 && r.For(index) == Light {
+				eg.bits[y*eg.stride+x/64] |= 1 << (x % 64)
 			}
 		}
 	}
+
 	return eg
 }
 
+const ruleSize = 512
+
 type EnhanceRule string
 
-func NewEnhanceRule(line string) EnhanceRule {
-	return EnhanceRule(line)
+func NewEnhanceRule(line string) (EnhanceRule, error) {
+	if len(line) != ruleSize {
+		return "", fmt.Errorf("the rule has %d characters instead of %d", len(line), ruleSize)
+	}
+	for i, c := range line {
+		if c != '#' && c != '.' {
+			return "", fmt.Errorf("rule character %d: unexpected %q", i+1, c)
+		}
+	}
+
+	return EnhanceRule(line), nil
 }
 
 func (e EnhanceRule) For(number int) color {
@@ -148,3 +240,49 @@ func (e EnhanceRule) For(number int) color {
 	}
 	return Dark
 }
+
+// NextOutside returns the color of the infinite outside of the image after an
+// enhancement: all its pixels have an index of 0 when dark, 511 when light.
+func (e EnhanceRule) NextOutside(outside color) color {
+	if outside == Light {
+		return e.For(ruleSize - 1)
+	}
+
+	return e.For(0)
+}
+
+// Background is how the outside of an image starting dark evolves.
+type Background int
+
+const (
+	// BackgroundDark stays dark.
+	BackgroundDark Background = iota
+	// BackgroundLight becomes light after the first enhancement, and stays
+	// light.
+	BackgroundLight
+	// BackgroundAlternating is light after odd enhancements, dark after even
+	// ones.
+	BackgroundAlternating
+)
+
+func (b Background) String() string {
+	switch b {
+	case BackgroundDark:
+		return "dark"
+	case BackgroundLight:
+		return "light"
+	default:
+		return "alternating"
+	}
+}
+
+func (e EnhanceRule) Background() Background {
+	switch {
+	case e.NextOutside(Dark) == Dark:
+		return BackgroundDark
+	case e.NextOutside(Light) == Light:
+		return BackgroundLight
+	default:
+		return BackgroundAlternating
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"image"
+	imgcolor "image/color"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/gverger/advent2021/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func example(t *testing.T) (EnhanceRule, Grid) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+
+	r, err := NewEnhanceRule(lines[0])
+	require.NoError(t, err)
+	g, err := NewGridFromInput(lines[2:])
+	require.NoError(t, err)
+
+	return r, g
+}
+
+func enhance(g Grid, r EnhanceRule, steps int) Grid {
+	for i := 0; i < steps; i++ {
+		g = g.Enhance(r)
+	}
+
+	return g
+}
+
+func TestEnhance(t *testing.T) {
+	tests := []struct {
+		steps    int
+		expected int
+	}{
+		{steps: 0, expected: 10},
+		{steps: 2, expected: 35},
+		{steps: 50, expected: 3351},
+	}
+
+	for _, test := range tests {
+		r, g := example(t)
+
+		nb, err := enhance(g, r, test.steps).Count()
+		require.NoError(t, err)
+		require.Equal(t, test.expected, nb, "%d steps", test.steps)
+	}
+}
+
+// naiveEnhance enhances pixel by pixel, without the bitset.
+func naiveEnhance(g Grid, r EnhanceRule) map[Point]color {
+	res := make(map[Point]color)
+	max := g.max()
+	for y := g.min.y - 1; y <= max.y+1; y++ {
+		for x := g.min.x - 1; x <= max.x+1; x++ {
+			index := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					index = index*2 + int(g.At(Point{x: x + dx, y: y + dy}))
+				}
+			}
+			res[Point{x: x, y: y}] = r.For(index)
+		}
+	}
+
+	return res
+}
+
+func TestEnhanceWideImage(t *testing.T) {
+	r, _ := example(t)
+	rng := rand.New(rand.NewSource(1))
+
+	// widths around 64 cross the word boundary of the rows while growing
+	for _, width := range []int{62, 63, 64, 130} {
+		g := NewGrid(Point{}, width, 4, Dark)
+		for _, p := range points(g) {
+			if rng.Intn(2) == 0 {
+				g.Set(p)
+			}
+		}
+
+		for step := 0; step < 3; step++ {
+			expected := naiveEnhance(g, r)
+			g = g.Enhance(r)
+			require.Equal(t, width+2*(step+1), g.width)
+			for p, c := range expected {
+				require.Equal(t, c, g.At(p), "width %d, step %d, pixel %v", width, step, p)
+			}
+		}
+	}
+}
+
+func points(g Grid) []Point {
+	res := make([]Point, 0, g.width*g.height)
+	max := g.max()
+	for y := g.min.y; y <= max.y; y++ {
+		for x := g.min.x; x <= max.x; x++ {
+			res = append(res, Point{x: x, y: y})
+		}
+	}
+
+	return res
+}
+
+func TestNewEnhanceRule(t *testing.T) {
+	r, _ := example(t)
+
+	tests := []struct {
+		name string
+		line string
+		err  string
+	}{
+		{name: "example", line: string(r)},
+		{name: "too short", line: string(r[1:]), err: "the rule has 511 characters instead of 512"},
+		{name: "too long", line: string(r) + ".", err: "the rule has 513 characters instead of 512"},
+		{name: "bad character", line: "..x" + string(r[3:]), err: "rule character 3: unexpected 'x'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewEnhanceRule(test.line)
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+// rule returns a rule with the given first and last characters.
+func rule(t *testing.T, first, last byte) EnhanceRule {
+	line := []byte(strings.Repeat(".", ruleSize))
+	line[0], line[ruleSize-1] = first, last
+
+	r, err := NewEnhanceRule(string(line))
+	require.NoError(t, err)
+
+	return r
+}
+
+func TestBackground(t *testing.T) {
+	tests := []struct {
+		first    byte
+		last     byte
+		expected Background
+		outside  []color
+	}{
+		{first: '.', last: '.', expected: BackgroundDark, outside: []color{Dark, Dark, Dark}},
+		{first: '.', last: '#', expected: BackgroundDark, outside: []color{Dark, Dark, Dark}},
+		{first: '#', last: '#', expected: BackgroundLight, outside: []color{Light, Light, Light}},
+		{first: '#', last: '.', expected: BackgroundAlternating, outside: []color{Light, Dark, Light}},
+	}
+
+	for _, test := range tests {
+		t.Run(string([]byte{test.first, test.last}), func(t *testing.T) {
+			r := rule(t, test.first, test.last)
+			require.Equal(t, test.expected, r.Background())
+
+			g := NewGrid(Point{}, 1, 1, Dark)
+			for _, outside := range test.outside {
+				g = g.Enhance(r)
+				require.Equal(t, outside, g.outside)
+				require.Equal(t, outside, g.At(Point{x: -100, y: 100}))
+			}
+		})
+	}
+}
+
+func TestCountInfinite(t *testing.T) {
+	g := NewGrid(Point{}, 2, 2, Dark)
+	g.Set(Point{x: 1, y: 1})
+
+	nb, err := g.Count()
+	require.NoError(t, err)
+	require.Equal(t, 1, nb)
+
+	g = g.Enhance(rule(t, '#', '#'))
+	_, err = g.Count()
+	require.EqualError(t, err, "infinite number of light pixels")
+}
+
+func TestRender(t *testing.T) {
+	g := NewGrid(Point{x: -1, y: -1}, 2, 2, Dark)
+	g.Set(Point{x: -1, y: -1})
+	g.Set(Point{x: 0, y: 0})
+
+	img := g.Render(g.Bounds(), 2)
+	require.Equal(t, image.Rect(0, 0, 4, 4), img.Bounds())
+
+	expected := []string{
+		"##..",
+		"##..",
+		"..##",
+		"..##",
+	}
+	for y, row := range expected {
+		for x, c := range row {
+			want := imgcolor.Gray{Y: 0}
+			if c == '#' {
+				want = imgcolor.Gray{Y: 255}
+			}
+			require.Equal(t, want, imgcolor.GrayModel.Convert(img.At(x, y)), "pixel %d,%d", x, y)
+		}
+	}
+
+	// pixels of the window out of the grid have the outside color
+	g.outside = Light
+	img = g.Render(image.Rect(-1, 0, 2, 1), 1)
+	require.Equal(t, uint8(0), img.ColorIndexAt(0, 0))
+	require.Equal(t, uint8(1), img.ColorIndexAt(1, 0))
+	require.Equal(t, uint8(1), img.ColorIndexAt(2, 0))
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected image.Rectangle
+		err      bool
+	}{
+		{text: "", expected: image.Rectangle{}},
+		{text: "0,0,10,5", expected: image.Rect(0, 0, 10, 5)},
+		{text: "-3,-2,4,1", expected: image.Rect(-3, -2, 4, 1)},
+		{text: "10,5,0,0", expected: image.Rect(0, 0, 10, 5)},
+		{text: "0,0,10", err: true},
+		{text: "0,0,10,5,1", err: true},
+		{text: "a,0,10,5", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			w, err := parseWindow(test.text)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, w)
+		})
+	}
+}
+
+func TestRunNegativeSteps(t *testing.T) {
+	lines, err := utils.ReadLines("test.txt")
+	require.NoError(t, err)
+
+	previous := *steps
+	*steps = -1
+	defer func() { *steps = previous }()
+
+	require.EqualError(t, run(lines), "steps -1 cannot be negative")
+}
+
+func TestEnhanceWithAutomaton(t *testing.T) {
+	r, g := example(t)
+
+	tests := []struct {
+		name string
+		rule EnhanceRule
+	}{
+		{name: "example", rule: r},
+		// the outside alternates between dark and light
+		{name: "alternating", rule: EnhanceRule("#" + string(r[1:ruleSize-1]) + ".")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected, actual := g, g
+			for step := 0; step < 4; step++ {
+				expected = expected.Enhance(test.rule)
+				actual = actual.EnhanceWithAutomaton(test.rule)
+
+				require.Equal(t, expected.Bounds(), actual.Bounds(), "step %d", step)
+				require.Equal(t, expected.outside, actual.outside, "step %d", step)
+				require.Equal(t, expected.String(), actual.String(), "step %d", step)
+			}
+		})
+	}
+}
+
+func TestAutomatonRoundTrip(t *testing.T) {
+	_, g := example(t)
+	g.outside = Light
+
+	a := g.Automaton()
+	require.Equal(t, g.width, a.Width)
+	require.Equal(t, g.height, a.Height)
+
+	back := NewGridFromAutomaton(a)
+	require.Equal(t, g.Bounds(), back.Bounds())
+	require.Equal(t, Light, back.outside)
+	require.Equal(t, g.String(), back.String())
+}
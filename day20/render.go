@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	imgcolor "image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gverger/advent2021/utils/maps"
+)
+
+var palette = imgcolor.Palette{imgcolor.Black, imgcolor.White}
+
+// Bounds are the coordinates of the pixels of the grid.
+func (g Grid) Bounds() image.Rectangle {
+	return image.Rect(g.min.x, g.min.y, g.min.x+g.width, g.min.y+g.height)
+}
+
+// Render draws the pixels of the window, each one as a scale x scale square.
+// Light pixels are white, dark ones black.
+func (g Grid) Render(window image.Rectangle, scale int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, window.Dx()*scale, window.Dy()*scale), palette)
+
+	for j := window.Min.Y; j < window.Max.Y; j++ {
+		for i := window.Min.X; i < window.Max.X; i++ {
+			if g.At(Point{x: i, y: j}) == Dark {
+				continue
+			}
+
+			x, y := (i-window.Min.X)*scale, (j-window.Min.Y)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x+dx, y+dy, 1)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// Renderer draws the grids of every step, as PNG files and/or as an animated
+// GIF. All the images show the same window.
+type Renderer struct {
+	Scale int
+	// Window is the part of the grids drawn, the whole last grid when empty.
+	Window image.Rectangle
+	// PNGDir receives a PNG file per step when not empty.
+	PNGDir string
+	// GIFFile receives all the steps when not empty.
+	GIFFile string
+
+	grids []Grid
+}
+
+func (r *Renderer) Enabled() bool {
+	return r.PNGDir != "" || r.GIFFile != ""
+}
+
+func (r *Renderer) Add(g Grid) {
+	r.grids = append(r.grids, g)
+}
+
+func (r *Renderer) Write() error {
+	if len(r.grids) == 0 {
+		return nil
+	}
+
+	window := r.Window
+	if window.Empty() {
+		// grids only grow, the last one contains all the others
+		window = r.grids[len(r.grids)-1].Bounds()
+	}
+
+	frames := make([]*image.Paletted, len(r.grids))
+	for i, g := range r.grids {
+		frames[i] = g.Render(window, r.Scale)
+	}
+
+	if r.PNGDir != "" {
+		for i, frame := range frames {
+			if err := writePNG(filepath.Join(r.PNGDir, fmt.Sprintf("step-%03d.png", i)), frame); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.GIFFile != "" {
+		return writeGIF(r.GIFFile, frames)
+	}
+
+	return nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create %q: %w", path, err)
+	}
+	defer f.Close()
+
+	return png.Encode(f, img)
+}
+
+func writeGIF(path string, frames []*image.Paletted) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create %q: %w", path, err)
+	}
+	defer f.Close()
+
+	anim := gif.GIF{Image: frames, Delay: make([]int, len(frames))}
+	for i := range anim.Delay {
+		anim.Delay[i] = 20
+	}
+
+	return gif.EncodeAll(f, &anim)
+}
+
+// parseWindow parses "x0,y0,x1,y1", the pixels from (x0,y0) included to
+// (x1,y1) excluded.
+func parseWindow(text string) (image.Rectangle, error) {
+	if text == "" {
+		return image.Rectangle{}, nil
+	}
+
+	coords, err := maps.Strings(strings.Split(text, ",")).ToInts()
+	if err != nil || len(coords) != 4 {
+		return image.Rectangle{}, fmt.Errorf("invalid window %q, expected x0,y0,x1,y1", text)
+	}
+
+	return image.Rect(coords[0], coords[1], coords[2], coords[3]), nil
+}
@@ -0,0 +1,129 @@
+package automaton
+
+// Rule updates a grid for one step.
+type Rule interface {
+	// Apply returns the cells that fired during the step, if the rule has
+	// such a notion.
+	Apply(g *Grid, n Neighbourhood) []Point
+}
+
+// Synchronous computes the next state of every cell at once, from the current
+// states of the cell and of its neighbours, in the order of the
+// neighbourhood.
+type Synchronous func(current State, neighbours []State) State
+
+func (r Synchronous) Apply(g *Grid, n Neighbourhood) []Point {
+	next := g.Clone()
+	if g.Boundary == Infinite {
+		radius := n.Radius()
+		next = NewGrid(g.Width+2*radius, g.Height+2*radius, Infinite)
+		next.Min = Point{X: g.Min.X - radius, Y: g.Min.Y - radius}
+
+		outside := make([]State, len(n))
+		for i := range outside {
+			outside[i] = g.Outside
+		}
+		next.Outside = r(g.Outside, outside)
+	}
+
+	states := make([]State, len(n))
+	for _, p := range next.Points() {
+		for i, offset := range n {
+			states[i] = g.At(p.Add(offset))
+		}
+		next.cells[next.index(p)] = r(g.At(p), states)
+	}
+
+	*g = *next
+
+	return nil
+}
+
+// Cascade updates every cell, then the cells that fire propagate to their
+// neighbours, which can fire in turn. A cell fires at most once per step.
+// Cells outside of the grid are ignored.
+type Cascade struct {
+	// Update is applied to every cell at the start of a step.
+	Update func(State) State
+	// Fires tells whether a cell fires.
+	Fires func(State) bool
+	// Fired is the state of a cell that fired, until the end of the step.
+	Fired State
+	// Propagate is applied to the neighbours of a firing cell that did not
+	// fire yet.
+	Propagate func(State) State
+}
+
+func (c Cascade) Apply(g *Grid, n Neighbourhood) []Point {
+	fired := make([]bool, len(g.cells))
+	res := make([]Point, 0)
+
+	update := func(p Point, fn func(State) State) {
+		i := g.index(p)
+		if fired[i] {
+			return
+		}
+
+		g.cells[i] = fn(g.cells[i])
+		if c.Fires(g.cells[i]) {
+			g.cells[i] = c.Fired
+			fired[i] = true
+			res = append(res, p)
+		}
+	}
+
+	for _, p := range g.Points() {
+		update(p, c.Update)
+	}
+
+	buffer := make([]Point, 0, len(n))
+	for k := 0; k < len(res); k++ {
+		buffer = g.neighbours(res[k], n, buffer)
+		for _, p := range buffer {
+			update(p, c.Propagate)
+		}
+	}
+
+	return res
+}
+
+type Automaton struct {
+	Grid          *Grid
+	Neighbourhood Neighbourhood
+	Rule          Rule
+	// Steps is the number of steps done.
+	Steps int
+}
+
+func New(g *Grid, n Neighbourhood, r Rule) *Automaton {
+	return &Automaton{Grid: g, Neighbourhood: n, Rule: r}
+}
+
+// Step updates the grid once, and returns the cells that fired.
+func (a *Automaton) Step() []Point {
+	a.Steps++
+
+	return a.Rule.Apply(a.Grid, a.Neighbourhood)
+}
+
+// Run does several steps, and returns the number of cells that fired.
+func (a *Automaton) Run(steps int) int {
+	nb := 0
+	for i := 0; i < steps; i++ {
+		nb += len(a.Step())
+	}
+
+	return nb
+}
+
+// RunUntil steps until done returns true, at most maxSteps times when
+// positive. It returns whether done returned true.
+func (a *Automaton) RunUntil(done func(a *Automaton, fired []Point) bool, maxSteps int) bool {
+	for i := 0; maxSteps <= 0 || i < maxSteps; i++ {
+		if done(a, a.Step()) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,148 @@
+package automaton
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func life(current State, neighbours []State) State {
+	alive := 0
+	for _, s := range neighbours {
+		alive += int(s)
+	}
+
+	if alive == 3 || (alive == 2 && current == 1) {
+		return 1
+	}
+
+	return 0
+}
+
+func gridFrom(lines []string, boundary Boundary) *Grid {
+	g := NewGrid(len(lines[0]), len(lines), boundary)
+	for y, l := range lines {
+		for x, c := range l {
+			switch {
+			case c == '#':
+				g.Set(Point{X: x, Y: y}, 1)
+			case c >= '0' && c <= '9':
+				g.Set(Point{X: x, Y: y}, State(c-'0'))
+			}
+		}
+	}
+
+	return g
+}
+
+func TestBlinker(t *testing.T) {
+	a := New(gridFrom([]string{".....", "..#..", "..#..", "..#..", "....."}, Finite), Moore, Synchronous(life))
+
+	a.Step()
+	require.Equal(t, gridFrom([]string{".....", ".....", ".###.", ".....", "....."}, Finite), a.Grid)
+
+	a.Step()
+	require.Equal(t, gridFrom([]string{".....", "..#..", "..#..", "..#..", "....."}, Finite), a.Grid)
+	require.Equal(t, 2, a.Steps)
+}
+
+func TestGliderWraps(t *testing.T) {
+	start := gridFrom([]string{".#....", "..#...", "###...", "......", "......", "......"}, Wrap)
+	a := New(start.Clone(), Moore, Synchronous(life))
+
+	// a glider moves by one cell diagonally every 4 steps
+	a.Run(4 * 6)
+
+	require.Equal(t, start, a.Grid)
+	require.Equal(t, 5, a.Grid.Count(1))
+}
+
+func TestInfinite(t *testing.T) {
+	// every cell becomes the opposite of the majority of its square
+	minority := func(_ State, neighbours []State) State {
+		alive := 0
+		for _, s := range neighbours {
+			alive += int(s)
+		}
+		if alive >= 5 {
+			return 0
+		}
+
+		return 1
+	}
+	a := New(gridFrom([]string{"#"}, Infinite), Square, Synchronous(minority))
+
+	a.Step()
+	require.Equal(t, Point{X: -1, Y: -1}, a.Grid.Min)
+	require.Equal(t, 3, a.Grid.Width)
+	require.Equal(t, State(1), a.Grid.Outside)
+	require.Equal(t, 9, a.Grid.Count(1))
+
+	a.Step()
+	require.Equal(t, 5, a.Grid.Width)
+	require.Equal(t, State(0), a.Grid.Outside)
+	require.Equal(t, 25, a.Grid.Count(0))
+}
+
+func octopuses(g *Grid) *Automaton {
+	incr := func(s State) State { return s + 1 }
+	flashes := Cascade{
+		Update:    incr,
+		Fires:     func(s State) bool { return s > 9 },
+		Fired:     0,
+		Propagate: incr,
+	}
+
+	return New(g, Moore, flashes)
+}
+
+func TestCascade(t *testing.T) {
+	a := octopuses(gridFrom([]string{
+		"11111",
+		"19991",
+		"19191",
+		"19991",
+		"11111",
+	}, Finite))
+
+	require.Len(t, a.Step(), 9)
+	require.Equal(t, gridFrom([]string{
+		"34543",
+		"40004",
+		"50005",
+		"40004",
+		"34543",
+	}, Finite), a.Grid)
+
+	require.Len(t, a.Step(), 0)
+}
+
+func TestRunUntil(t *testing.T) {
+	a := octopuses(gridFrom([]string{
+		"5483143223",
+		"2745854711",
+		"5264556173",
+		"6141336146",
+		"6357385478",
+		"4167524645",
+		"2176841721",
+		"6882881134",
+		"4846848554",
+		"5283751526",
+	}, Finite))
+
+	require.Equal(t, 1656, a.Run(100))
+
+	allFlash := func(a *Automaton, fired []Point) bool { return len(fired) == 100 }
+	require.False(t, a.RunUntil(allFlash, 10))
+	require.True(t, a.RunUntil(allFlash, 0))
+	require.Equal(t, 195, a.Steps)
+}
+
+func TestWrapNeighbours(t *testing.T) {
+	g := NewGrid(4, 3, Wrap)
+
+	require.ElementsMatch(t, []Point{{3, 2}, {0, 2}, {1, 2}, {3, 0}, {1, 0}, {3, 1}, {0, 1}, {1, 1}},
+		g.neighbours(Point{}, Moore, nil))
+	require.Len(t, NewGrid(4, 3, Finite).neighbours(Point{}, Moore, nil), 3)
+}
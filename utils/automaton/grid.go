@@ -0,0 +1,158 @@
+package automaton
+
+type State int
+
+type Point struct {
+	X int
+	Y int
+}
+
+func (p Point) Add(other Point) Point {
+	return Point{X: p.X + other.X, Y: p.Y + other.Y}
+}
+
+// Neighbourhood lists the offsets of the neighbours of a cell.
+type Neighbourhood []Point
+
+var (
+	// Moore is the 8 cells around a cell.
+	Moore = Neighbourhood{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}}
+	// VonNeumann is the 4 cells sharing a side with a cell.
+	VonNeumann = Neighbourhood{{0, -1}, {-1, 0}, {1, 0}, {0, 1}}
+	// Square is the 3x3 square centered on a cell, row by row, the cell
+	// included.
+	Square = Neighbourhood{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {0, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}}
+)
+
+// Radius is the largest distance on an axis between a cell and a neighbour.
+func (n Neighbourhood) Radius() int {
+	r := 0
+	for _, p := range n {
+		for _, d := range []int{p.X, -p.X, p.Y, -p.Y} {
+			if d > r {
+				r = d
+			}
+		}
+	}
+
+	return r
+}
+
+type Boundary int
+
+const (
+	// Finite grids have no cell outside of them. Synchronous rules see the
+	// Outside state there.
+	Finite Boundary = iota
+	// Wrap grids are toroidal: leaving by a side enters by the other one.
+	Wrap
+	// Infinite grids are surrounded by cells all in the Outside state. They
+	// grow by the radius of the neighbourhood on each synchronous step.
+	Infinite
+)
+
+// Grid is a rectangle of cells, the top left one being at Min.
+type Grid struct {
+	Min      Point
+	Width    int
+	Height   int
+	Boundary Boundary
+	Outside  State
+
+	cells []State
+}
+
+func NewGrid(width, height int, boundary Boundary) *Grid {
+	return &Grid{Width: width, Height: height, Boundary: boundary, cells: make([]State, width*height)}
+}
+
+func (g *Grid) Contains(p Point) bool {
+	x, y := p.X-g.Min.X, p.Y-g.Min.Y
+
+	return x >= 0 && y >= 0 && x < g.Width && y < g.Height
+}
+
+// resolve returns the cell at p, wrapping around for Wrap grids, and false
+// when there is no such cell.
+func (g *Grid) resolve(p Point) (Point, bool) {
+	if g.Boundary == Wrap {
+		return Point{X: g.Min.X + mod(p.X-g.Min.X, g.Width), Y: g.Min.Y + mod(p.Y-g.Min.Y, g.Height)}, true
+	}
+
+	return p, g.Contains(p)
+}
+
+func mod(a, b int) int {
+	return ((a % b) + b) % b
+}
+
+func (g *Grid) index(p Point) int {
+	return (p.Y-g.Min.Y)*g.Width + p.X - g.Min.X
+}
+
+func (g *Grid) At(p Point) State {
+	p, ok := g.resolve(p)
+	if !ok {
+		return g.Outside
+	}
+
+	return g.cells[g.index(p)]
+}
+
+// Set changes the state of a cell, nothing happening outside of a Finite or
+// Infinite grid.
+func (g *Grid) Set(p Point, s State) {
+	p, ok := g.resolve(p)
+	if ok {
+		g.cells[g.index(p)] = s
+	}
+}
+
+// Cells returns the states of the cells row by row. The slice is owned by the
+// grid.
+func (g *Grid) Cells() []State {
+	return g.cells
+}
+
+// Points returns the cells of the grid row by row.
+func (g *Grid) Points() []Point {
+	res := make([]Point, 0, len(g.cells))
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			res = append(res, Point{X: g.Min.X + x, Y: g.Min.Y + y})
+		}
+	}
+
+	return res
+}
+
+// Count returns the number of cells in the state, outside cells excluded.
+func (g *Grid) Count(s State) int {
+	nb := 0
+	for _, c := range g.cells {
+		if c == s {
+			nb++
+		}
+	}
+
+	return nb
+}
+
+func (g *Grid) Clone() *Grid {
+	c := *g
+	c.cells = append([]State(nil), g.cells...)
+
+	return &c
+}
+
+// neighbours returns the existing neighbours of p.
+func (g *Grid) neighbours(p Point, n Neighbourhood, buffer []Point) []Point {
+	buffer = buffer[:0]
+	for _, offset := range n {
+		if q, ok := g.resolve(p.Add(offset)); ok {
+			buffer = append(buffer, q)
+		}
+	}
+
+	return buffer
+}